@@ -0,0 +1,131 @@
+package main
+
+import (
+	"./proto"
+	"encoding/xml"
+	"github.com/kellydunn/golang-geo"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	AIRSIGMET_URL = "https://aviationweather.gov/api/data/airsigmet?format=xml"
+)
+
+// AIRSIGMET mirrors an <AIRSIGMET> element of the aviationweather.gov XML feed.
+type AIRSIGMET struct {
+	Text          string    `xml:"raw_text"`
+	ValidTimeFrom time.Time `xml:"valid_time_from"`
+	ValidTimeTo   time.Time `xml:"valid_time_to"`
+	Altitude      struct {
+		MinFtMSL int `xml:"min_ft_msl,attr"`
+		MaxFtMSL int `xml:"max_ft_msl,attr"`
+	} `xml:"altitude"`
+	Hazard struct {
+		Type     string `xml:"type,attr"`
+		Severity string `xml:"severity,attr"`
+	} `xml:"hazard"`
+	Type string `xml:"airsigmet_type"`
+	feedArea
+}
+
+// feedArea is the hazard area of the AIRMET/SIGMET and G-AIRMET feeds.
+type feedArea struct {
+	Points []feedPoint `xml:"area>point"`
+}
+
+type feedPoint struct {
+	Latitude  float64 `xml:"latitude"`
+	Longitude float64 `xml:"longitude"`
+}
+
+type airsigmetResponse struct {
+	AIRSIGMETs []AIRSIGMET `xml:"data>AIRSIGMET"`
+}
+
+var airsigmetHazards = map[string]txwx.Hazard{
+	"CONVECTIVE": txwx.Hazard_CONVECTIVE,
+	"TURB":       txwx.Hazard_TURBULENCE,
+	"ICE":        txwx.Hazard_ICING,
+	"IFR":        txwx.Hazard_IFR,
+	"MTN OBSCN":  txwx.Hazard_MTN_OBSCN,
+	"ASH":        txwx.Hazard_ASH,
+	"LLWS":       txwx.Hazard_LLWS,
+	"SFC_WND":    txwx.Hazard_SFC_WIND,
+}
+
+var airsigmetSeverities = map[string]uint32{
+	"LT-MOD":  1,
+	"LGT":     1,
+	"MOD":     2,
+	"MOD-SEV": 3,
+	"SEV":     3,
+}
+
+var airsigmetIdentRe = regexp.MustCompile(`(?:SIGMET|AIRMET)\s+([A-Z]*\s?[0-9]+[A-Z]?|[A-Z]+)`)
+
+func (a feedArea) Polygon() []*geo.Point {
+	points := make([]*geo.Point, 0, len(a.Points))
+	for _, p := range a.Points {
+		points = append(points, geo.NewPoint(p.Latitude, p.Longitude))
+	}
+	// The feed closes the ring by repeating the first vertex. We don't need it.
+	if len(points) > 1 && points[0].Lat() == points[len(points)-1].Lat() && points[0].Lng() == points[len(points)-1].Lng() {
+		points = points[:len(points)-1]
+	}
+	return points
+}
+
+func (a AIRSIGMET) Ident() string {
+	m := airsigmetIdentRe.FindStringSubmatch(a.Text)
+	if m == nil {
+		return ""
+	}
+	return strings.Replace(m[1], " ", "", -1)
+}
+
+// getAIRSIGMETsInRadiusOf fetches all current AIRMETs and SIGMETs and keeps those whose polygon
+// overlaps the circle of radius statute miles around p.
+func getAIRSIGMETsInRadiusOf(radius float64, p *geo.Point) ([]AIRSIGMET, error) {
+	body, err := readSource(AIRSIGMET_URL)
+	if err != nil {
+		return nil, err
+	}
+	var r airsigmetResponse
+	err = xml.Unmarshal(body, &r)
+	if err != nil {
+		return nil, err
+	}
+	var ret []AIRSIGMET
+	for _, a := range r.AIRSIGMETs {
+		if polygonIntersectsCircle(a.Polygon(), p, radius*KM_PER_SM) {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
+
+func createAIRSIGMETWeatherMessage(a AIRSIGMET) *txwx.WeatherMessage {
+	msgType := txwx.WeatherMessage_AIRMET
+	if a.Type == "SIGMET" {
+		msgType = txwx.WeatherMessage_SIGMET
+	}
+	return &txwx.WeatherMessage{
+		Type:            msgType,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(a.ValidTimeFrom.Unix()),
+		ValidFrom:       uint32(a.ValidTimeFrom.Unix()),
+		ValidTo:         uint32(a.ValidTimeTo.Unix()),
+		Ident:           a.Ident(),
+		Hazard:          airsigmetHazards[a.Hazard.Type],
+		Severity:        airsigmetSeverities[a.Hazard.Severity],
+		Area: &txwx.Area{
+			Polygon: encodePolygon(reducePolygon(a.Polygon(), MAX_POLYGON_VERTICES)),
+			Floor:   uint32(a.Altitude.MinFtMSL / 100),
+			Ceiling: uint32(a.Altitude.MaxFtMSL / 100),
+		},
+	}
+}
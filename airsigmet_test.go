@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"github.com/kellydunn/golang-geo"
+	"testing"
+)
+
+const testAIRSIGMETXML = `<response><data>
+<AIRSIGMET>
+  <raw_text>WSUS32 KKCI 191455 SIGMET 12C VALID UNTIL 191655Z</raw_text>
+  <airsigmet_type>SIGMET</airsigmet_type>
+  <altitude min_ft_msl="0" max_ft_msl="45000"/>
+  <hazard type="CONVECTIVE" severity="SEV"/>
+  <area num_points="4">
+    <point><longitude>-82.0</longitude><latitude>44.0</latitude></point>
+    <point><longitude>-81.0</longitude><latitude>44.0</latitude></point>
+    <point><longitude>-81.5</longitude><latitude>44.5</latitude></point>
+    <point><longitude>-82.0</longitude><latitude>44.0</latitude></point>
+  </area>
+</AIRSIGMET>
+</data></response>`
+
+const testGAIRMETXML = `<response><data>
+<GAIRMET>
+  <product>TANGO</product>
+  <tag>2E</tag>
+  <forecast_hour>3</forecast_hour>
+  <hazard type="TURB-LO" severity="MOD"/>
+  <altitude min_ft_msl="0" max_ft_msl="18000"/>
+  <area num_points="3">
+    <point><longitude>-82.0</longitude><latitude>44.0</latitude></point>
+    <point><longitude>-81.0</longitude><latitude>44.0</latitude></point>
+    <point><longitude>-81.5</longitude><latitude>44.5</latitude></point>
+  </area>
+</GAIRMET>
+</data></response>`
+
+func TestFeedAreaPolygon(t *testing.T) {
+	var a airsigmetResponse
+	if err := xml.Unmarshal([]byte(testAIRSIGMETXML), &a); err != nil {
+		t.Fatal(err)
+	}
+	var g gairmetResponse
+	if err := xml.Unmarshal([]byte(testGAIRMETXML), &g); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.AIRSIGMETs) != 1 || len(g.GAIRMETs) != 1 {
+		t.Fatalf("%d AIRSIGMETs, %d G-AIRMETs", len(a.AIRSIGMETs), len(g.GAIRMETs))
+	}
+	if id := a.AIRSIGMETs[0].Ident(); id != "12C" {
+		t.Errorf("AIRSIGMET ident %q", id)
+	}
+	if id := g.GAIRMETs[0].Ident(); id != "TANGO 2E +3" {
+		t.Errorf("G-AIRMET ident %q", id)
+	}
+	// The closing vertex repeating the first is dropped, an open ring is kept as is.
+	for name, poly := range map[string]feedArea{"AIRSIGMET": a.AIRSIGMETs[0].feedArea, "GAIRMET": g.GAIRMETs[0].feedArea} {
+		points := poly.Polygon()
+		if len(points) != 3 || points[0].Lat() != 44.0 || points[0].Lng() != -82.0 || points[2].Lat() != 44.5 {
+			t.Errorf("%s polygon %v", name, points)
+		}
+	}
+}
+
+func TestAIRSIGMETAreaContainsHazard(t *testing.T) {
+	a := AIRSIGMET{Type: "SIGMET"}
+	star := circlePoints(geo.NewPoint(44.25, -81.60), 30, 40, true) // Concave.
+	for _, p := range star {
+		a.Points = append(a.Points, feedPoint{Latitude: p.Lat(), Longitude: p.Lng()})
+	}
+	a.Points = append(a.Points, a.Points[0]) // Closed, as in the feed.
+	sent, err := decodePolygon(createAIRSIGMETWeatherMessage(a).Area.Polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) > MAX_POLYGON_VERTICES {
+		t.Errorf("%d vertices sent", len(sent))
+	}
+	for i, p := range star {
+		if !insideConvex(sent, p) {
+			t.Errorf("vertex %d %s not inside the sent area", i, formatLatLng(p))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"./proto"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DUTY_CYCLE_WINDOW = 1 * time.Hour // Duty cycle limits are per channel and per hour.
+)
+
+// radioMode describes the over-the-air format of a RadioModMode: raw bit rate, fixed per-packet
+// overhead (preamble, sync word, length) and the FEC expansion of the payload.
+type radioMode struct {
+	Bitrate  float64 // Bits per second.
+	Overhead int     // Bytes.
+	FEC      float64 // Coded bytes per payload byte.
+}
+
+// radioModes are the nominal rates of the dongle firmware. RadioBitrate overrides the rate for
+// firmware that differs.
+var radioModes = map[byte]radioMode{
+	0: {Bitrate: 10000, Overhead: 12, FEC: 2},
+	1: {Bitrate: 50000, Overhead: 12, FEC: 2},
+	2: {Bitrate: 100000, Overhead: 12, FEC: 2},
+	3: {Bitrate: 250000, Overhead: 12, FEC: 1},
+}
+
+// validateModModes checks that every mode we may switch to is one we know the airtime of, and that
+// every radio has a slot for the products with a mode of their own.
+func validateModModes() error {
+	for _, c := range radioConfigs() {
+		if _, ok := radioModes[c.RadioModMode]; !ok {
+			return fmt.Errorf("radio %s: unknown RadioModMode %d", c.Name, c.RadioModMode)
+		}
+		for i, e := range c.FreqScheme {
+			if _, ok := radioModes[e.ModMode]; !ok {
+				return fmt.Errorf("radio %s: FreqScheme entry %d has unknown ModMode %d", c.Name, i, e.ModMode)
+			}
+		}
+	}
+	for name, m := range globalSettings.ProductModModes {
+		if _, ok := txwx.WeatherMessage_Type_value[name]; !ok {
+			return fmt.Errorf("ProductModModes: unknown message type %s", name)
+		}
+		if _, ok := radioModes[m]; !ok {
+			return fmt.Errorf("ProductModModes: unknown mode %d for %s", m, name)
+		}
+		configs := radioConfigs()
+		for _, i := range radioIndexesFor(configs, txwx.WeatherMessage_Type(txwx.WeatherMessage_Type_value[name])) {
+			found := false
+			for _, e := range configs[i].scheme() {
+				found = found || e.ModMode == m
+			}
+			if !found {
+				return fmt.Errorf("ProductModModes: radio %s sends %s, but has no FreqScheme slot in mode %d", configs[i].Name, name, m)
+			}
+		}
+	}
+	return nil
+}
+
+// longestPacketAirtime is the airtime of a MAX_PACKET_LEN packet in the slowest mode any radio may
+// switch to.
+func longestPacketAirtime() time.Duration {
+	var longest time.Duration
+	check := func(m byte) {
+		if a := packetAirtime(m, MAX_PACKET_LEN); a > longest {
+			longest = a
+		}
+	}
+	for _, c := range radioConfigs() {
+		for _, e := range c.scheme() {
+			check(e.ModMode)
+		}
+	}
+	for _, m := range globalSettings.ProductModModes {
+		check(m)
+	}
+	return longest
+}
+
+// packetAirtime estimates how long a packet of n bytes is on the air in the given mode.
+func packetAirtime(modMode byte, n int) time.Duration {
+	m, ok := radioModes[modMode]
+	if !ok {
+		m = radioModes[1]
+	}
+	if globalSettings.RadioBitrate > 0 {
+		m.Bitrate = globalSettings.RadioBitrate
+	}
+	bits := (float64(m.Overhead) + float64(n)*m.FEC) * 8
+	return time.Duration(bits / m.Bitrate * float64(time.Second))
+}
+
+type airtimeUse struct {
+	Time    time.Time
+	Airtime time.Duration
+}
+
+// airtimeLimiter enforces the duty cycle in every sub-band we transmit in. The limits apply to a
+// sub-band as a whole, however many of our hop channels are in it. Each sub-band may spend
+// dutyCycle * DUTY_CYCLE_WINDOW in any window, counted from the transmissions themselves, so no
+// sliding hour can go over.
+type airtimeLimiter struct {
+	mu    *sync.Mutex
+	bands map[subBand][]airtimeUse // Within the last DUTY_CYCLE_WINDOW, oldest first.
+}
+
+var txLimiter = &airtimeLimiter{mu: &sync.Mutex{}, bands: make(map[subBand][]airtimeUse)}
+
+// recent drops what has left the window and returns the rest.
+func (l *airtimeLimiter) recent(band subBand, now time.Time) []airtimeUse {
+	cutoff := now.Add(-DUTY_CYCLE_WINDOW)
+	uses := l.bands[band]
+	for len(uses) > 0 && !uses[0].Time.After(cutoff) {
+		uses = uses[1:]
+	}
+	l.bands[band] = uses
+	return uses
+}
+
+// reserve books airtime d in the sub-band if the window has room for it, and returns 0. Otherwise
+// nothing is booked and it returns how long until enough of the past airtime has left the window.
+// A dutyCycle of 0 means no limit.
+func (l *airtimeLimiter) reserve(band subBand, dutyCycle float64, d time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := sysClock.Now()
+	uses := l.recent(band, now)
+	if dutyCycle > 0 {
+		var used time.Duration
+		for _, u := range uses {
+			used += u.Airtime
+		}
+		excess := used + d - time.Duration(dutyCycle*float64(DUTY_CYCLE_WINDOW))
+		if excess > 0 {
+			for _, u := range uses {
+				excess -= u.Airtime
+				if excess <= 0 {
+					return u.Time.Add(DUTY_CYCLE_WINDOW).Sub(now)
+				}
+			}
+			return DUTY_CYCLE_WINDOW // Longer than the whole allowance.
+		}
+	}
+	l.bands[band] = append(uses, airtimeUse{Time: now, Airtime: d})
+	return 0
+}
+
+// usedIn returns the airtime spent in band within DUTY_CYCLE_WINDOW.
+func (l *airtimeLimiter) usedIn(band subBand) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var used time.Duration
+	for _, u := range l.recent(band, sysClock.Now()) {
+		used += u.Airtime
+	}
+	return used
+}
+
+// usedLastHour returns the airtime spent in all sub-bands within DUTY_CYCLE_WINDOW.
+func (l *airtimeLimiter) usedLastHour() time.Duration {
+	var used time.Duration
+	for _, b := range l.bandsUsed() {
+		used += l.usedIn(b)
+	}
+	return used
+}
+
+// bandsUsed lists the sub-bands transmitted in, lowest first.
+func (l *airtimeLimiter) bandsUsed() []subBand {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bands := make([]subBand, 0, len(l.bands))
+	for b := range l.bands {
+		bands = append(bands, b)
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].Start < bands[j].Start })
+	return bands
+}
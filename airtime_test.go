@@ -0,0 +1,173 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLimiter() *airtimeLimiter {
+	return &airtimeLimiter{mu: &sync.Mutex{}, bands: make(map[subBand][]airtimeUse)}
+}
+
+func euBand(freq float64) subBand {
+	b, _ := bandPlans["EU"].subBandOf(freq)
+	return b
+}
+
+func TestLimiterSharesSubBand(t *testing.T) {
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	l := newTestLimiter()
+	// 868.1, 868.3 and 868.5 MHz are all in 868.0-868.6, which gets 1% between them.
+	for _, f := range []float64{868.1, 868.3, 868.5} {
+		if wait := l.reserve(euBand(f), 0.01, 12*time.Second); wait != 0 {
+			t.Fatalf("%0.1f MHz refused", f)
+		}
+	}
+	if wait := l.reserve(euBand(868.1), 0.01, time.Second); wait == 0 {
+		t.Errorf("sub-band allowance used up, but another channel in it was accepted")
+	}
+}
+
+func TestLimiterStartsEmpty(t *testing.T) {
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	l := newTestLimiter()
+	// 1% of an hour is 36 s: 36 one second packets fit, the 37th waits for the first to age out.
+	for i := 0; i < 36; i++ {
+		if wait := l.reserve(euBand(868.3), 0.01, time.Second); wait != 0 {
+			t.Fatalf("packet %d refused, wait %s", i, wait)
+		}
+		c.advance(time.Second)
+	}
+	wait := l.reserve(euBand(868.3), 0.01, time.Second)
+	if want := DUTY_CYCLE_WINDOW - 36*time.Second; wait != want {
+		t.Fatalf("wait = %s, want %s", wait, want)
+	}
+	c.advance(wait)
+	if wait := l.reserve(euBand(868.3), 0.01, time.Second); wait != 0 {
+		t.Errorf("still refused after the first packet left the window, wait %s", wait)
+	}
+}
+
+func TestLimiterSlidingWindow(t *testing.T) {
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	l := newTestLimiter()
+	const dutyCycle = 0.01
+	airtime := 200 * time.Millisecond
+	var sent []time.Time
+	// A sender that transmits whenever allowed, for three hours.
+	for end := c.Now().Add(3 * time.Hour); c.Now().Before(end); {
+		wait := l.reserve(euBand(868.3), dutyCycle, airtime)
+		if wait == 0 {
+			sent = append(sent, c.Now())
+			wait = airtime
+		}
+		c.advance(wait)
+	}
+	allowed := time.Duration(dutyCycle * float64(DUTY_CYCLE_WINDOW))
+	for i := range sent {
+		var used time.Duration
+		for j := i; j < len(sent) && sent[j].Sub(sent[i]) < DUTY_CYCLE_WINDOW; j++ {
+			used += airtime
+		}
+		if used > allowed {
+			t.Fatalf("%s in the hour from %s, allowed %s", used, sent[i], allowed)
+		}
+	}
+	if got, want := len(sent), 3*int(allowed/airtime); got != want {
+		t.Errorf("sent %d packets in three hours, want %d", got, want)
+	}
+}
+
+func TestLimiterChannelsAndNoLimit(t *testing.T) {
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	l := newTestLimiter()
+	l.reserve(euBand(868.3), 0.01, 36*time.Second)
+	if wait := l.reserve(euBand(868.3), 0.01, time.Millisecond); wait == 0 {
+		t.Errorf("full channel accepted more")
+	}
+	if wait := l.reserve(euBand(869.5), 0.01, time.Second); wait != 0 {
+		t.Errorf("other channel refused")
+	}
+	if wait := l.reserve(subBand{Start: 902.0, End: 928.0}, 0, time.Hour); wait != 0 {
+		t.Errorf("unlimited channel refused")
+	}
+	if used := l.usedIn(euBand(868.3)); used != 36*time.Second {
+		t.Errorf("usedIn(868.0-868.6) = %s", used)
+	}
+	if used := l.usedLastHour(); used != 36*time.Second+time.Second+time.Hour {
+		t.Errorf("usedLastHour() = %s", used)
+	}
+	if bands := l.bandsUsed(); len(bands) != 3 || bands[0].Start != 868.0 {
+		t.Errorf("bandsUsed() = %v", bands)
+	}
+	c.advance(DUTY_CYCLE_WINDOW)
+	if used := l.usedLastHour(); used != 0 {
+		t.Errorf("usedLastHour() = %s after an hour", used)
+	}
+}
+
+func TestPacketAirtime(t *testing.T) {
+	resetSettings()
+	tests := []struct {
+		mode byte
+		n    int
+		want time.Duration
+	}{
+		{0, 100, time.Duration((12 + 200) * 8 * float64(time.Second) / 10000)},
+		{1, 100, time.Duration((12 + 200) * 8 * float64(time.Second) / 50000)},
+		{3, 100, time.Duration((12 + 100) * 8 * float64(time.Second) / 250000)},
+	}
+	for _, tt := range tests {
+		if got := packetAirtime(tt.mode, tt.n); got != tt.want {
+			t.Errorf("packetAirtime(%d, %d) = %s, want %s", tt.mode, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestValidateModModes(t *testing.T) {
+	hopping := []FreqSchemeEntry{{Freq: 915.0, Dwell: 1000, ModMode: 0}, {Freq: 915.0, Dwell: 4000, ModMode: 3}}
+	tests := []struct {
+		name   string
+		modes  map[string]byte
+		radios []RadioConfig
+		ok     bool
+	}{
+		{"none", nil, nil, true},
+		{"unknown type", map[string]byte{"FOO": 0}, nil, false},
+		{"unknown mode", map[string]byte{"TAF": 9}, nil, false},
+		{"no slot in mode", map[string]byte{"TAF": 3}, nil, false},
+		{"slot in mode", map[string]byte{"BEACON": 0, "TAF": 3}, []RadioConfig{{Name: "a", RadioModMode: 1, FreqScheme: hopping}}, true},
+		{"other radio sends it", map[string]byte{"TAF": 3}, []RadioConfig{
+			{Name: "a", RadioModMode: 1, Products: []string{"METAR"}},
+			{Name: "b", RadioModMode: 1, FreqScheme: hopping},
+		}, true},
+		{"emergency on every radio", map[string]byte{"EMERGENCY": 0}, []RadioConfig{
+			{Name: "a", RadioModMode: 1, Products: []string{"METAR"}},
+			{Name: "b", RadioModMode: 1, FreqScheme: hopping},
+		}, false},
+	}
+	for _, tt := range tests {
+		resetSettings()
+		globalSettings.ProductModModes = tt.modes
+		globalSettings.Radios = tt.radios
+		if err := validateModModes(); (err == nil) != tt.ok {
+			t.Errorf("%s: validateModModes() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestLongestPacketAirtime(t *testing.T) {
+	resetSettings()
+	if a := longestPacketAirtime(); a != packetAirtime(1, MAX_PACKET_LEN) {
+		t.Errorf("default radio: %s", a)
+	}
+	globalSettings.ProductModModes = map[string]byte{"BEACON": 0}
+	if a := longestPacketAirtime(); a != packetAirtime(0, MAX_PACKET_LEN) {
+		t.Errorf("with BEACON in mode 0: %s", a)
+	}
+}
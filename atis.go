@@ -0,0 +1,247 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"fmt"
+	"github.com/cyoung/ADDS"
+	"io/ioutil"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	ATIS_CALM_WIND  = 3            // Knots. At or below this the calm wind runway is used.
+	ATIS_WORST_WIND = "36099G99KT" // Longest wind group we size the notes for.
+)
+
+// ATISOverride is an entry of the operator's override file, e.g.
+//
+//	[{"Ident": "K1A2", "Runway": "09", "Notes": "RWY 27 CLSD FOR MOWING", "Expires": "2019-10-19T18:00:00Z"}]
+//
+// Empty fields keep the automatic values, a missing Expires means until removed from the file.
+type ATISOverride struct {
+	Ident   string
+	Runway  string
+	Pattern string
+	Notes   string
+	Expires time.Time
+}
+
+func (o ATISOverride) Active(t time.Time) bool {
+	return o.Expires.IsZero() || t.Before(o.Expires)
+}
+
+// loadATISOverrides reads the override file. Overrides whose notes don't fit in one packet with the
+// rest of their airport's broadcast are logged and skipped.
+func loadATISOverrides(fn string) ([]ATISOverride, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var overrides []ATISOverride
+	err = json.Unmarshal(buf, &overrides)
+	if err != nil {
+		return nil, err
+	}
+	var ret []ATISOverride
+	for _, o := range overrides {
+		if a := findAirport(o.Ident); a != nil {
+			notes := strings.TrimSpace(a.Notes + " " + o.Notes)
+			if room := atisNotesRoom(*a, o.Runway); len(notes) > room {
+				log.Printf("runway override for %s: notes are too long (%d bytes with the airport's, %d max), skipping.\n", o.Ident, len(notes), room)
+				continue
+			}
+		}
+		ret = append(ret, o)
+	}
+	return ret, nil
+}
+
+func findAirport(ident string) *AirportConfig {
+	for i := range globalSettings.Airports {
+		if globalSettings.Airports[i].Ident == ident {
+			return &globalSettings.Airports[i]
+		}
+	}
+	return nil
+}
+
+// atisNotesRoom is how many bytes of notes fit in one packet after the longest text the airport's
+// runways (or the override runway) and the wind can produce.
+func atisNotesRoom(a AirportConfig, overrideRunway string) int {
+	worst := a
+	worst.Notes = ""
+	worst.Runways = []RunwayConfig{{Name: overrideRunway, Pattern: "R"}}
+	for _, r := range a.Runways {
+		if len(r.Name) > len(worst.Runways[0].Name) {
+			worst.Runways[0].Name = r.Name
+		}
+	}
+	metar := &ADDS.ADDSMETAR{Text: a.Ident + " 191200Z " + ATIS_WORST_WIND}
+	msg := createATISWeatherMessage(worst, atisText(worst, metar, nil), time.Unix(math.MaxUint32, 0))
+	return MAX_PACKET_LEN - 1 - packetSize(msg) - len(" ")
+}
+
+// validateAirports checks that the airports' Notes leave room for the rest of the broadcast.
+func validateAirports() error {
+	for _, a := range globalSettings.Airports {
+		if room := atisNotesRoom(a, ""); len(a.Notes) > room {
+			return fmt.Errorf("airport %s: Notes are too long (%d bytes, %d max)", a.Ident, len(a.Notes), room)
+		}
+	}
+	return nil
+}
+
+// headwind is the headwind component of wind from dir at speed on a runway with the given heading.
+func headwind(dir, speed, heading int) float64 {
+	return float64(speed) * math.Cos(float64(dir-heading)*math.Pi/180.0)
+}
+
+// selectRunway picks the runway with the most headwind, or the calm wind runway in light and variable
+// wind. nil if the airport has no runways configured.
+func selectRunway(a AirportConfig, f metarFields) *RunwayConfig {
+	if len(a.Runways) == 0 {
+		return nil
+	}
+	best := &a.Runways[0]
+	if f.WindDir < 0 || f.WindSpeed <= ATIS_CALM_WIND {
+		for i := range a.Runways {
+			if a.Runways[i].Calm {
+				return &a.Runways[i]
+			}
+		}
+		return best
+	}
+	for i := range a.Runways {
+		if headwind(f.WindDir, f.WindSpeed, a.Runways[i].Heading) > headwind(f.WindDir, f.WindSpeed, best.Heading) {
+			best = &a.Runways[i]
+		}
+	}
+	return best
+}
+
+func findRunway(a AirportConfig, name string) *RunwayConfig {
+	for i := range a.Runways {
+		if a.Runways[i].Name == name {
+			return &a.Runways[i]
+		}
+	}
+	return nil
+}
+
+// atisText builds the broadcast text, e.g. "RWY 27 RIGHT TFC WIND 27010G18KT CTAF 122.8 PPR FOR GLIDER OPS".
+// Automatically selected runways are marked "AUTO". Returns "" when there is nothing to say.
+func atisText(a AirportConfig, metar *ADDS.ADDSMETAR, override *ATISOverride) string {
+	var f metarFields
+	if metar != nil {
+		f = parseMETARText(metar.Text)
+	}
+	var words []string
+	var rwy *RunwayConfig
+	pattern := ""
+	if override != nil && len(override.Runway) > 0 {
+		rwy = findRunway(a, override.Runway)
+		words = append(words, "RWY "+override.Runway)
+	} else if metar != nil && f.HasWind {
+		rwy = selectRunway(a, f)
+		if rwy != nil {
+			words = append(words, "AUTO RWY "+rwy.Name)
+		}
+	}
+	if rwy != nil {
+		pattern = rwy.Pattern
+	}
+	if override != nil && len(override.Pattern) > 0 {
+		pattern = override.Pattern
+	}
+	switch strings.ToUpper(pattern) {
+	case "L":
+		words = append(words, "LEFT TFC")
+	case "R":
+		words = append(words, "RIGHT TFC")
+	}
+	if len(words) == 0 && (override == nil || len(override.Notes) == 0) {
+		return ""
+	}
+	if f.HasWind {
+		switch {
+		case f.WindSpeed == 0:
+			words = append(words, "WIND CALM")
+		case f.WindDir < 0:
+			words = append(words, fmt.Sprintf("WIND VRB%02dKT", f.WindSpeed))
+		case f.WindGust > 0:
+			words = append(words, fmt.Sprintf("WIND %03d%02dG%02dKT", f.WindDir, f.WindSpeed, f.WindGust))
+		default:
+			words = append(words, fmt.Sprintf("WIND %03d%02dKT", f.WindDir, f.WindSpeed))
+		}
+	}
+	if len(a.CTAF) > 0 {
+		words = append(words, "CTAF "+a.CTAF)
+	}
+	if len(a.Notes) > 0 {
+		words = append(words, a.Notes)
+	}
+	if override != nil && len(override.Notes) > 0 {
+		words = append(words, override.Notes)
+	}
+	return strings.Join(words, " ")
+}
+
+// createATISWeatherMessages builds the runway in use broadcast for every configured airport, from the
+// latest METARs and the active overrides.
+func createATISWeatherMessages(metars []ADDS.ADDSMETAR, overrides []ATISOverride) []*txwx.WeatherMessage {
+	now := sysClock.Now()
+	var msgs []*txwx.WeatherMessage
+	for _, a := range globalSettings.Airports {
+		var metar *ADDS.ADDSMETAR
+		for i := range metars {
+			if metars[i].StationID == a.Ident {
+				metar = &metars[i]
+				break
+			}
+		}
+		var override *ATISOverride
+		for i := range overrides {
+			if overrides[i].Ident == a.Ident && overrides[i].Active(now) {
+				override = &overrides[i]
+			}
+		}
+		text := atisText(a, metar, override)
+		if len(text) == 0 {
+			continue
+		}
+		observed := now
+		if metar != nil && override == nil {
+			observed = metar.Observation.Time
+		}
+		msg := createATISWeatherMessage(a, text, observed)
+		if packetSize(msg) >= MAX_PACKET_LEN {
+			// An unusual wind group. Cut the notes at the end rather than not send the runway.
+			for packetSize(msg) >= MAX_PACKET_LEN && len(msg.TextData) > 0 {
+				i := strings.LastIndex(msg.TextData, " ")
+				if i < 0 {
+					i = len(msg.TextData) - 1
+				}
+				msg.TextData = msg.TextData[:i]
+			}
+			log.Printf("ATIS %s: text cut to \"%s\" to fit in one packet.\n", a.Ident, msg.TextData)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func createATISWeatherMessage(a AirportConfig, text string, observed time.Time) *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_ATIS,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(observed.Unix()),
+		Ident:           a.Ident,
+		TextData:        text,
+	}
+}
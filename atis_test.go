@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/cyoung/ADDS"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAirport(notes string) AirportConfig {
+	return AirportConfig{Ident: "K1A2", CTAF: "122.8", Notes: notes, Runways: []RunwayConfig{
+		{Name: "09", Heading: 90, Pattern: "L"},
+		{Name: "27", Heading: 270, Pattern: "R", Calm: true},
+	}}
+}
+
+func TestATISText(t *testing.T) {
+	resetSettings()
+	a := testAirport("PPR FOR GLIDER OPS")
+	tests := []struct {
+		metar    string
+		override *ATISOverride
+		want     string
+	}{
+		{"K1A2 191200Z 09010KT 10SM CLR 20/10 A2992", nil, "AUTO RWY 09 LEFT TFC WIND 09010KT CTAF 122.8 PPR FOR GLIDER OPS"},
+		{"K1A2 191200Z 25012G20KT 10SM CLR 20/10 A2992", nil, "AUTO RWY 27 RIGHT TFC WIND 25012G20KT CTAF 122.8 PPR FOR GLIDER OPS"},
+		{"K1A2 191200Z 00000KT 10SM CLR 20/10 A2992", nil, "AUTO RWY 27 RIGHT TFC WIND CALM CTAF 122.8 PPR FOR GLIDER OPS"},
+		{"K1A2 191200Z VRB03KT 10SM CLR 20/10 A2992", nil, "AUTO RWY 27 RIGHT TFC WIND VRB03KT CTAF 122.8 PPR FOR GLIDER OPS"},
+		{"K1A2 191200Z 09010KT 10SM CLR 20/10 A2992", &ATISOverride{Runway: "27", Notes: "RWY 09 CLSD"}, "RWY 27 RIGHT TFC WIND 09010KT CTAF 122.8 PPR FOR GLIDER OPS RWY 09 CLSD"},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		var metar *ADDS.ADDSMETAR
+		if len(tt.metar) > 0 {
+			metar = &ADDS.ADDSMETAR{Text: tt.metar}
+		}
+		if got := atisText(a, metar, tt.override); got != tt.want {
+			t.Errorf("%q: %q, want %q", tt.metar, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAirports(t *testing.T) {
+	resetSettings()
+	room := atisNotesRoom(testAirport(""), "")
+	if room < 40 || room > MAX_PACKET_LEN {
+		t.Fatalf("room for notes = %d", room)
+	}
+	tests := []struct {
+		notes string
+		ok    bool
+	}{
+		{"", true},
+		{"PPR FOR GLIDER OPS", true},
+		{strings.Repeat("X", room), true},
+		{strings.Repeat("X", room+1), false},
+	}
+	for _, tt := range tests {
+		globalSettings.Airports = []AirportConfig{testAirport(tt.notes)}
+		if err := validateAirports(); (err == nil) != tt.ok {
+			t.Errorf("%d bytes of notes: validateAirports() = %v", len(tt.notes), err)
+		}
+	}
+}
+
+func TestLoadATISOverridesSkipsLongNotes(t *testing.T) {
+	resetSettings()
+	globalSettings.Airports = []AirportConfig{testAirport("PPR FOR GLIDER OPS")}
+	dir, err := ioutil.TempDir("", "txwx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "overrides.json")
+	err = ioutil.WriteFile(fn, []byte(`[
+		{"Ident": "K1A2", "Runway": "09", "Notes": "RWY 27 CLSD FOR MOWING"},
+		{"Ident": "K1A2", "Notes": "`+strings.Repeat("X", 100)+`"},
+		{"Ident": "K9Z9", "Notes": "NOT CONFIGURED"}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := loadATISOverrides(fn)
+	if err != nil {
+		t.Fatalf("loadATISOverrides: %s", err.Error())
+	}
+	if len(overrides) != 2 || overrides[0].Runway != "09" || overrides[1].Ident != "K9Z9" {
+		t.Errorf("loaded %+v", overrides)
+	}
+}
+
+func TestATISMessageFits(t *testing.T) {
+	resetSettings()
+	a := testAirport("")
+	a.Notes = strings.Repeat("X", atisNotesRoom(a, ""))
+	globalSettings.Airports = []AirportConfig{a}
+	now := time.Unix(1571500000, 0)
+	c := newFakeClock(now)
+	defer useClock(c)()
+	tests := []struct {
+		wind string
+		cut  bool
+	}{
+		{"09010KT", false},
+		{ATIS_WORST_WIND, false},
+		{"090120G150KT", true}, // Longer than we size for.
+	}
+	for _, tt := range tests {
+		metars := []ADDS.ADDSMETAR{{StationID: "K1A2", Text: "K1A2 191200Z " + tt.wind + " 10SM CLR 20/10 A2992", Observation: ADDS.ADDSTime{Time: now}}}
+		msgs := createATISWeatherMessages(metars, nil)
+		if len(msgs) != 1 {
+			t.Fatalf("%s: %d messages", tt.wind, len(msgs))
+		}
+		if n := packetSize(msgs[0]); n >= MAX_PACKET_LEN {
+			t.Errorf("%s: packet is %d bytes", tt.wind, n)
+		}
+		if cut := !strings.HasSuffix(msgs[0].TextData, a.Notes); cut != tt.cut || !strings.HasPrefix(msgs[0].TextData, "AUTO RWY ") {
+			t.Errorf("%s: text %q", tt.wind, msgs[0].TextData)
+		}
+	}
+}
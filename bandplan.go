@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// subBand is a part of a band plan with its own duty cycle limit (0 = none).
+type subBand struct {
+	Start     float64 // MHz.
+	End       float64 // MHz.
+	DutyCycle float64
+}
+
+// bandPlan is the spectrum we may use in a region. If it has sub-bands, only they may be used.
+type bandPlan struct {
+	Name     string
+	Start    float64 // MHz.
+	End      float64 // MHz.
+	SubBands []subBand
+}
+
+// bandPlans are selected by BandPlan in txwx.conf. EU sub-bands follow ERC/REC 70-03 annex 1.
+var bandPlans = map[string]bandPlan{
+	"US": {Name: "US", Start: 902.0, End: 928.0},
+	"EU": {Name: "EU", Start: 863.0, End: 870.0, SubBands: []subBand{
+		{Start: 863.0, End: 865.0, DutyCycle: 0.001},
+		{Start: 865.0, End: 868.0, DutyCycle: 0.01},
+		{Start: 868.0, End: 868.6, DutyCycle: 0.01},
+		{Start: 868.7, End: 869.2, DutyCycle: 0.001},
+		{Start: 869.4, End: 869.65, DutyCycle: 0.1},
+		{Start: 869.7, End: 870.0, DutyCycle: 0.01},
+	}},
+	"AU": {Name: "AU", Start: 915.0, End: 928.0},
+}
+
+func currentBandPlan() bandPlan {
+	return bandPlans[globalSettings.BandPlan]
+}
+
+// subBandOf finds the part of the plan freq falls in. ok is false if we may not transmit on freq.
+func (p bandPlan) subBandOf(freq float64) (subBand, bool) {
+	if freq < p.Start || freq > p.End {
+		return subBand{}, false
+	}
+	if len(p.SubBands) == 0 {
+		return subBand{Start: p.Start, End: p.End}, true
+	}
+	for _, s := range p.SubBands {
+		if freq >= s.Start && freq <= s.End {
+			return s, true
+		}
+	}
+	return subBand{}, false
+}
+
+// dutyCycleFor is the duty cycle limit on freq: the stricter of the sub-band's and DutyCycle.
+func dutyCycleFor(freq float64) float64 {
+	limit := globalSettings.DutyCycle
+	s, _ := currentBandPlan().subBandOf(freq)
+	if s.DutyCycle > 0 && (limit <= 0 || s.DutyCycle < limit) {
+		limit = s.DutyCycle
+	}
+	return limit
+}
+
+// validateBandPlan checks Freq and the hop schedule against the configured band plan.
+func validateBandPlan() error {
+	p, ok := bandPlans[globalSettings.BandPlan]
+	if !ok {
+		return errors.New("unknown BandPlan \"" + globalSettings.BandPlan + "\", should be US, EU or AU")
+	}
+	for _, c := range radioConfigs() {
+		if _, ok := p.subBandOf(c.Freq); !ok {
+			return fmt.Errorf("radio %s: Freq %0.3f MHz is outside the %s band plan", c.Name, c.Freq, p.Name)
+		}
+		for i, e := range c.FreqScheme {
+			if _, ok := p.subBandOf(e.Freq); !ok {
+				return fmt.Errorf("radio %s: FreqScheme entry %d (%0.3f MHz) is outside the %s band plan", c.Name, i, e.Freq, p.Name)
+			}
+			if e.Dwell <= 0 {
+				return fmt.Errorf("radio %s: FreqScheme entry %d has no dwell time", c.Name, i)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSubBandOf(t *testing.T) {
+	tests := []struct {
+		plan  string
+		freq  float64
+		ok    bool
+		start float64
+	}{
+		{"US", 915.0, true, 902.0},
+		{"US", 868.3, false, 0},
+		{"EU", 868.3, true, 868.0},
+		{"EU", 868.65, false, 0}, // Between sub-bands.
+		{"EU", 869.525, true, 869.4},
+		{"AU", 910.0, false, 0},
+	}
+	for _, tt := range tests {
+		b, ok := bandPlans[tt.plan].subBandOf(tt.freq)
+		if ok != tt.ok || b.Start != tt.start {
+			t.Errorf("%s subBandOf(%0.3f) = %v, %t", tt.plan, tt.freq, b, ok)
+		}
+	}
+}
+
+func TestDutyCycleFor(t *testing.T) {
+	resetSettings()
+	globalSettings.BandPlan = "EU"
+	tests := []struct {
+		configured float64
+		freq       float64
+		want       float64
+	}{
+		{0, 868.3, 0.01},
+		{0, 869.5, 0.1},
+		{0.05, 869.5, 0.05}, // Stricter than the sub-band.
+		{0.05, 868.3, 0.01},
+	}
+	for _, tt := range tests {
+		globalSettings.DutyCycle = tt.configured
+		if got := dutyCycleFor(tt.freq); got != tt.want {
+			t.Errorf("DutyCycle %v: dutyCycleFor(%0.3f) = %v, want %v", tt.configured, tt.freq, got, tt.want)
+		}
+	}
+}
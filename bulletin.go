@@ -0,0 +1,138 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"github.com/kellydunn/golang-geo"
+	"log"
+	"time"
+)
+
+// Bulletin is a free-text operator advisory, e.g.
+//
+//	[{"Ident": "FLYIN", "Text": "FLY-IN SAT RWY 09 CLSD 1400-1600Z", "Start": "2019-10-19T00:00:00Z",
+//	  "End": "2019-10-20T00:00:00Z", "Interval": 60, "Lat": 44.25, "Lng": -81.60, "Radius": 50}]
+//
+// Interval is the repeat interval in seconds, 0 to send it with every pass. Radius (statute miles
+// around Lat/Lng) optionally limits where the bulletin applies, 0 = everywhere.
+type Bulletin struct {
+	Ident    string
+	Text     string
+	Start    time.Time
+	End      time.Time
+	Interval int
+	Lat      float64
+	Lng      float64
+	Radius   float64
+}
+
+func (b Bulletin) Active(t time.Time) bool {
+	if t.Before(b.Start) {
+		return false
+	}
+	return b.End.IsZero() || t.Before(b.End)
+}
+
+// InScope checks if the bulletin's area overlaps the circle of radius statute miles around p.
+func (b Bulletin) InScope(radius float64, p *geo.Point) bool {
+	if b.Radius <= 0 {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	return p.GreatCircleDistance(geo.NewPoint(b.Lat, b.Lng)) <= (radius+b.Radius)*KM_PER_SM
+}
+
+// Key identifies a bulletin for repeat scheduling across reloads.
+func (b Bulletin) Key() string {
+	return b.Ident + "|" + b.Text
+}
+
+// bulletinSent records when a bulletin last went out, by time and by carousel pass.
+type bulletinSent struct {
+	Time time.Time
+	Pass int
+}
+
+// dueBulletins picks the bulletins in scope that are due in carousel pass number pass, and records
+// them in sent. A bulletin with an Interval goes out once per Interval, one without once per pass.
+// Bulletins no longer in the list are forgotten.
+func dueBulletins(bulletins []Bulletin, sent map[string]bulletinSent, pass int) []Bulletin {
+	now := sysClock.Now()
+	current := make(map[string]bool)
+	for _, v := range bulletins {
+		current[v.Key()] = true
+	}
+	for k := range sent {
+		if !current[k] {
+			delete(sent, k) // Removed from the file.
+		}
+	}
+	var due []Bulletin
+	for _, v := range bulletins {
+		if !v.Active(now) || !v.InScope(globalSettings.CoverageRadius, stationGeoPt) {
+			continue
+		}
+		if last, ok := sent[v.Key()]; ok {
+			if v.Interval <= 0 && last.Pass == pass {
+				continue
+			}
+			if v.Interval > 0 && now.Sub(last.Time) < time.Duration(v.Interval)*time.Second {
+				continue
+			}
+		}
+		sent[v.Key()] = bulletinSent{Time: now, Pass: pass}
+		due = append(due, v)
+	}
+	return due
+}
+
+// loadBulletins reads the bulletin list from a local file, or from an HTTP endpoint if source is a URL.
+// Bulletins too long for one packet, with their scope and times, are logged and skipped.
+func loadBulletins(source string) ([]Bulletin, error) {
+	buf, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	var bulletins []Bulletin
+	err = json.Unmarshal(buf, &bulletins)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Bulletin
+	for _, b := range bulletins {
+		if n := packetSize(createBulletinWeatherMessage(b)); n >= MAX_PACKET_LEN {
+			log.Printf("bulletin %s is too long (%d bytes, %d max), skipping.\n", b.Ident, n, MAX_PACKET_LEN-1)
+			continue
+		}
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
+
+func createBulletinWeatherMessage(b Bulletin) *txwx.WeatherMessage {
+	msg := &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_BULLETIN,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(sysClock.Now().Unix()),
+		Ident:           b.Ident,
+		TextData:        b.Text,
+	}
+	if !b.Start.IsZero() {
+		msg.ObservationTime = uint32(b.Start.Unix())
+		msg.ValidFrom = uint32(b.Start.Unix())
+	}
+	if !b.End.IsZero() {
+		msg.ValidTo = uint32(b.End.Unix())
+	}
+	if b.Radius > 0 {
+		msg.Area = &txwx.Area{
+			Polygon: encodePolygon([]*geo.Point{geo.NewPoint(b.Lat, b.Lng)}),
+			Radius:  uint32(b.Radius*KM_PER_SM/KM_PER_NM*10 + 0.5),
+		}
+	}
+	return msg
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"hash/crc64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadBulletinsSkipsOversize(t *testing.T) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	dir, err := ioutil.TempDir("", "txwx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	long := strings.Repeat("X", 95) // Used to fit the text limit, but not with a scope and times.
+	fn := filepath.Join(dir, "bulletins.json")
+	err = ioutil.WriteFile(fn, []byte(`[
+		{"Ident": "FLYIN", "Text": "FLY-IN SAT RWY 09 CLSD 1400-1600Z"},
+		{"Ident": "LONG", "Text": "`+long+`", "Start": "2019-10-19T00:00:00Z", "End": "2019-10-20T00:00:00Z", "Lat": 44.25, "Lng": -81.60, "Radius": 50},
+		{"Ident": "SCOPED", "Text": "PARACHUTING 3NM S", "Start": "2019-10-19T00:00:00Z", "End": "2019-10-20T00:00:00Z", "Lat": 44.25, "Lng": -81.60, "Radius": 50}
+	]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bulletins, err := loadBulletins(fn)
+	if err != nil {
+		t.Fatalf("loadBulletins: %s", err.Error())
+	}
+	if len(bulletins) != 2 || bulletins[0].Ident != "FLYIN" || bulletins[1].Ident != "SCOPED" {
+		t.Fatalf("loaded %+v", bulletins)
+	}
+	for _, b := range bulletins {
+		if n := len(preparePacketFromWeatherMessage(createBulletinWeatherMessage(b))); n >= MAX_PACKET_LEN {
+			t.Errorf("bulletin %s loaded, but its packet is %d bytes", b.Ident, n)
+		}
+	}
+}
+
+func TestBulletinScope(t *testing.T) {
+	start := time.Date(2019, 10, 19, 0, 0, 0, 0, time.UTC)
+	b := Bulletin{Ident: "FLYIN", Start: start, End: start.Add(24 * time.Hour), Lat: 44.25, Lng: -81.60, Radius: 50}
+	if b.Active(start.Add(-time.Second)) || !b.Active(start) || b.Active(start.Add(24*time.Hour)) {
+		t.Errorf("Active() wrong around Start/End")
+	}
+	near := geo.NewPoint(44.25, -82.60) // About 50 sm west.
+	far := geo.NewPoint(44.25, -86.60)
+	if !b.InScope(10, near) || b.InScope(10, far) || b.InScope(10, nil) {
+		t.Errorf("InScope() wrong")
+	}
+	if !(Bulletin{}).InScope(10, nil) {
+		t.Errorf("unscoped bulletin out of scope")
+	}
+}
+
+func TestDueBulletins(t *testing.T) {
+	resetSettings()
+	c := newFakeClock(time.Date(2019, 10, 19, 12, 0, 0, 0, time.UTC))
+	defer useClock(c)()
+	bulletins := []Bulletin{
+		{Ident: "EVERY", Text: "RWY 09 CLSD"},
+		{Ident: "MINUTE", Text: "FLY-IN SAT", Interval: 60},
+	}
+	sent := make(map[string]bulletinSent)
+	idents := func(bs []Bulletin) string {
+		var s []string
+		for _, b := range bs {
+			s = append(s, b.Ident)
+		}
+		return strings.Join(s, " ")
+	}
+	tests := []struct {
+		pass    int
+		advance time.Duration
+		want    string
+	}{
+		{1, 0, "EVERY MINUTE"},
+		{1, time.Second, ""}, // Checked again after the next report, same pass.
+		{1, 10 * time.Second, ""},
+		{2, time.Second, "EVERY"},
+		{2, 50 * time.Second, "MINUTE"},
+		{2, time.Minute, "MINUTE"},
+		{3, 0, "EVERY"},
+	}
+	for i, tt := range tests {
+		c.advance(tt.advance)
+		if got := idents(dueBulletins(bulletins, sent, tt.pass)); got != tt.want {
+			t.Errorf("%d: pass %d at %s sent %q, want %q", i, tt.pass, c.Now().Format("15:04:05"), got, tt.want)
+		}
+	}
+	dueBulletins(bulletins[:1], sent, 4)
+	if _, ok := sent[bulletins[1].Key()]; ok {
+		t.Errorf("removed bulletin not forgotten")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"./proto"
+	"fmt"
+	"time"
+)
+
+func productCadence(t txwx.WeatherMessage_Type) ProductCadence {
+	return globalSettings.Cadence[t.String()]
+}
+
+// fetchInterval is how often the product's source is refreshed, def unless configured.
+func fetchInterval(t txwx.WeatherMessage_Type, def time.Duration) time.Duration {
+	if s := productCadence(t).FetchInterval; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return def
+}
+
+// sendInterval is the minimum time between sends of the product, def unless configured.
+func sendInterval(t txwx.WeatherMessage_Type, def time.Duration) time.Duration {
+	if s := productCadence(t).Cadence; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return def
+}
+
+func repetitions(t txwx.WeatherMessage_Type) int {
+	if n := productCadence(t).Repetitions; n > 0 {
+		return n
+	}
+	return globalSettings.Scheduler.BurstCount
+}
+
+// tooOld checks if a report observed at observed is past the product's MaxAge.
+func tooOld(t txwx.WeatherMessage_Type, observed time.Time, now time.Time) bool {
+	m := productCadence(t).MaxAge
+	return m > 0 && !observed.IsZero() && now.Sub(observed) > time.Duration(m)*time.Minute
+}
+
+// cadenceGate holds back products sent per pass until their Cadence has passed.
+type cadenceGate struct {
+	last map[txwx.WeatherMessage_Type]time.Time
+}
+
+func newCadenceGate() *cadenceGate {
+	return &cadenceGate{last: make(map[txwx.WeatherMessage_Type]time.Time)}
+}
+
+// due checks if the product goes out in this pass, and if so starts its next interval.
+func (g *cadenceGate) due(t txwx.WeatherMessage_Type, now time.Time) bool {
+	if now.Sub(g.last[t]) < sendInterval(t, 0) {
+		return false
+	}
+	g.last[t] = now
+	return true
+}
+
+func validateCadence() error {
+	for name, c := range globalSettings.Cadence {
+		if _, ok := txwx.WeatherMessage_Type_value[name]; !ok {
+			return fmt.Errorf("Cadence: unknown message type %s", name)
+		}
+		if c.FetchInterval < 0 || c.Cadence < 0 || c.Repetitions < 0 || c.MaxAge < 0 {
+			return fmt.Errorf("Cadence: negative value for %s", name)
+		}
+	}
+	return nil
+}
+
+// expired checks a message against its product's MaxAge.
+func expired(msg *txwx.WeatherMessage, now time.Time) bool {
+	if msg.ObservationTime == 0 {
+		return false
+	}
+	return tooOld(msg.Type, time.Unix(int64(msg.ObservationTime), 0), now)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"./proto"
+	"testing"
+	"time"
+)
+
+// useCadence resets the settings with cadence as the only product overrides.
+func useCadence(cadence map[string]ProductCadence) {
+	resetSettings()
+	globalSettings.Cadence = cadence
+}
+
+func TestCadenceIntervals(t *testing.T) {
+	useCadence(map[string]ProductCadence{
+		"METAR":       {Repetitions: 5, MaxAge: 90},
+		"WINDS_ALOFT": {FetchInterval: 10800, Cadence: 60},
+	})
+	tests := []struct {
+		typ         txwx.WeatherMessage_Type
+		fetch, send time.Duration
+		reps        int
+	}{
+		{txwx.WeatherMessage_METAR, time.Minute, 0, 5},
+		{txwx.WeatherMessage_WINDS_ALOFT, 3 * time.Hour, time.Minute, 3},
+		{txwx.WeatherMessage_TAF, time.Minute, 0, 3}, // Not configured, all defaults.
+	}
+	for _, tt := range tests {
+		if got := fetchInterval(tt.typ, time.Minute); got != tt.fetch {
+			t.Errorf("fetchInterval(%s) = %s, want %s", tt.typ, got, tt.fetch)
+		}
+		if got := sendInterval(tt.typ, 0); got != tt.send {
+			t.Errorf("sendInterval(%s) = %s, want %s", tt.typ, got, tt.send)
+		}
+		if got := repetitions(tt.typ); got != tt.reps {
+			t.Errorf("repetitions(%s) = %d, want %d", tt.typ, got, tt.reps)
+		}
+	}
+}
+
+func TestTooOld(t *testing.T) {
+	useCadence(map[string]ProductCadence{"METAR": {MaxAge: 90}})
+	now := time.Unix(100000, 0)
+	tests := []struct {
+		name     string
+		typ      txwx.WeatherMessage_Type
+		observed time.Time
+		want     bool
+	}{
+		{"fresh", txwx.WeatherMessage_METAR, now.Add(-30 * time.Minute), false},
+		{"at MaxAge", txwx.WeatherMessage_METAR, now.Add(-90 * time.Minute), false},
+		{"past MaxAge", txwx.WeatherMessage_METAR, now.Add(-91 * time.Minute), true},
+		{"no observation time", txwx.WeatherMessage_METAR, time.Time{}, false},
+		{"no MaxAge", txwx.WeatherMessage_TAF, now.Add(-24 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := tooOld(tt.typ, tt.observed, now); got != tt.want {
+			t.Errorf("%s: tooOld = %t", tt.name, got)
+		}
+		msg := &txwx.WeatherMessage{Type: tt.typ}
+		if !tt.observed.IsZero() {
+			msg.ObservationTime = uint32(tt.observed.Unix())
+		}
+		if got := expired(msg, now); got != tt.want {
+			t.Errorf("%s: expired = %t", tt.name, got)
+		}
+	}
+}
+
+func TestCadenceGate(t *testing.T) {
+	useCadence(map[string]ProductCadence{"WINDS_ALOFT": {Cadence: 60}})
+	g := newCadenceGate()
+	now := time.Unix(100000, 0)
+	tests := []struct {
+		typ  txwx.WeatherMessage_Type
+		at   time.Duration
+		want bool
+	}{
+		{txwx.WeatherMessage_WINDS_ALOFT, 0, true},
+		{txwx.WeatherMessage_WINDS_ALOFT, 30 * time.Second, false},
+		{txwx.WeatherMessage_WINDS_ALOFT, 59 * time.Second, false},
+		{txwx.WeatherMessage_WINDS_ALOFT, 60 * time.Second, true},
+		{txwx.WeatherMessage_WINDS_ALOFT, 90 * time.Second, false}, // The interval restarted at 60s.
+		{txwx.WeatherMessage_NOTAM, 0, true},                       // No Cadence, every pass.
+		{txwx.WeatherMessage_NOTAM, time.Second, true},
+	}
+	for _, tt := range tests {
+		if got := g.due(tt.typ, now.Add(tt.at)); got != tt.want {
+			t.Errorf("%s at %s: due = %t", tt.typ, tt.at, got)
+		}
+	}
+}
+
+func TestValidateCadence(t *testing.T) {
+	tests := []struct {
+		name    string
+		cadence map[string]ProductCadence
+		ok      bool
+	}{
+		{"none", nil, true},
+		{"good", map[string]ProductCadence{"METAR": {Repetitions: 5, MaxAge: 90}, "BEACON": {Cadence: 30}}, true},
+		{"unknown type", map[string]ProductCadence{"PIREP": {Cadence: 60}}, false},
+		{"lower case", map[string]ProductCadence{"metar": {Cadence: 60}}, false},
+		{"negative fetch interval", map[string]ProductCadence{"METAR": {FetchInterval: -1}}, false},
+		{"negative cadence", map[string]ProductCadence{"TAF": {Cadence: -60}}, false},
+		{"negative repetitions", map[string]ProductCadence{"TAF": {Repetitions: -1}}, false},
+		{"negative max age", map[string]ProductCadence{"METAR": {MaxAge: -90}}, false},
+	}
+	for _, tt := range tests {
+		useCadence(tt.cadence)
+		if err := validateCadence(); (err == nil) != tt.ok {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
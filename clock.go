@@ -0,0 +1,21 @@
+package main
+
+import (
+	"time"
+)
+
+// clock is the time source of everything that decides when to transmit: beacons, the carousel,
+// hopping, TDMA and the duty cycle limiter. Swapping in a fake one makes their timing deterministic.
+type clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var sysClock clock = systemClock{}
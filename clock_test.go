@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when advanced. Timers due by then fire in order.
+type fakeClock struct {
+	mu     *sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	At time.Time
+	C  chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{mu: &sync.Mutex{}, now: now}
+}
+
+// useClock makes c the clock until the returned function is called.
+func useClock(c clock) func() {
+	old := sysClock
+	sysClock = c
+	return func() { sysClock = old }
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{At: c.now.Add(d), C: ch})
+	return ch
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// advance moves the clock forward by d, a timer at a time, so that whoever wakes up can set the next
+// timer before the clock moves past it.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		next := -1
+		for i, t := range c.timers {
+			if !t.At.After(end) && (next < 0 || t.At.Before(c.timers[next].At)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[next]
+		c.timers = append(c.timers[:next], c.timers[next+1:]...)
+		if t.At.After(c.now) {
+			c.now = t.At
+		}
+		n := len(c.timers)
+		c.mu.Unlock()
+		t.C <- t.At
+		c.waitTimers(n + 1) // The sleeper either sets a new timer or is done.
+	}
+}
+
+// waitTimers waits, briefly, for goroutines to be blocked on n timers.
+func (c *fakeClock) waitTimers(n int) {
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		k := len(c.timers)
+		c.mu.Unlock()
+		if k >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	c := newFakeClock(start)
+	a := c.After(2 * time.Second)
+	b := c.After(time.Second)
+	c.advance(500 * time.Millisecond)
+	select {
+	case <-a:
+		t.Fatalf("timer fired early")
+	case <-b:
+		t.Fatalf("timer fired early")
+	default:
+	}
+	c.advance(time.Second)
+	if at := <-b; !at.Equal(start.Add(time.Second)) {
+		t.Errorf("timer fired at %s, want %s", at, start.Add(time.Second))
+	}
+	select {
+	case <-a:
+		t.Fatalf("timer fired early")
+	default:
+	}
+	c.advance(time.Second)
+	<-a
+	if !c.Now().Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Now() = %s", c.Now())
+	}
+	select {
+	case <-c.After(0):
+	default:
+		t.Errorf("After(0) didn't fire right away")
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"github.com/kellydunn/golang-geo"
@@ -9,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,8 +27,150 @@ type settings struct {
 	Mode         int
 	RadioModMode byte
 	Freq         float64
+	BandPlan     string  // Regional band plan, "US", "EU" or "AU". Freq and FreqScheme must fall inside it.
 	ManualLat    float64 // Manually configured location.
 	ManualLng    float64 // Manually configured location.
+
+	RadioBitrate    float64           // Bits per second on the air, for the airtime model. 0 = nominal rate of RadioModMode.
+	FreqScheme      []FreqSchemeEntry // Hop schedule. Empty = stay on Freq, see FreqSchemeEntry.
+	Radios          []RadioConfig     // Several TX dongles. Empty = one, on Freq/RadioModMode/FreqScheme and the -productID flag.
+	ProductModModes map[string]byte   // Modulation mode by message type, e.g. {"BEACON": 0, "EMERGENCY": 0, "TAF": 3}. These only go out in FreqScheme slots of that mode, others in any slot.
+	DutyCycle       float64           // Max fraction of each hour we may transmit on a channel, e.g. 0.01. 0 = no limit. The band plan may be stricter.
+
+	StationID string // Names this station, e.g. for the TDMA slot. Required for TDMA unless TDMASlot is set.
+	TDMAFrame int    // Milliseconds. 0 = no TDMA, transmit whenever.
+	TDMASlots int    // Slots per frame.
+	TDMASlot  int    // Our slot, 0-based. -1 = derived from StationID.
+	TDMAGuard int    // Milliseconds left unused at the end of each slot.
+
+	LBTMode         string // Listen-before-talk: "" = off, "rssi" = TX radio RSSI, "rx" = paired RX dongle (hears only UAT weather stations).
+	LBTProductID    int    // USB product ID of the paired RX dongle.
+	LBTThreshold    int    // dBm. The channel is busy at or above this.
+	LBTMinBackoff   int    // Milliseconds.
+	LBTMaxBackoff   int    // Milliseconds.
+	LBTMaxDeferrals int    // A message is dropped after this many busy samples in a row.
+
+	CoverageRadius float64 // Statute miles. Area products (AIRMETs, SIGMETs, TFRs) are sent when they overlap this radius.
+	NOTAMPath      string  // Operator maintained NOTAM file, or a directory of them.
+	TFRPath        string  // Operator maintained GeoJSON file of TFRs.
+	BulletinSource string  // Operator bulletins, a local file or an http(s) URL.
+
+	SensorSource    string  // Local weather station feed, e.g. "udp:50222". Empty = no sensor. See readFeed().
+	SensorIdent     string  // Airport ident used for the synthesized METARs.
+	SensorElevation float64 // Feet MSL, for the altimeter setting.
+
+	LightningSource string // Lightning strike feed, e.g. "udp:50223". Empty = no lightning. See readFeed().
+	RadarPath       string // Operator supplied precipitation PNG, see radarRaster.
+
+	METARSources []METARSource // Extra METAR sources, see METARSource. None by default.
+
+	EmergencyPath     string // Operator emergency notices, see Emergency.
+	EmergencyKey      string // Shared secret authenticating emergency messages. Must match on TX and RX.
+	EmergencyDuration int    // Minutes an emergency notice is broadcast for, unless it sets its own End.
+	EmergencyInterval int    // Seconds between repeats of an active emergency notice.
+
+	GAIRMETSource string // G-AIRMET XML, a local file or an http(s) URL. Empty = aviationweather.gov.
+	GAIRMETWeight int    // One G-AIRMET snapshot goes out per GAIRMETWeight METARs/TAFs.
+
+	WindsAloftSource string // FB winds aloft bulletin, a local file or an http(s) URL. Empty = aviationweather.gov.
+
+	Scheduler SchedulerConfig           // Carousel weights, see SchedulerConfig.
+	Cadence   map[string]ProductCadence // By message type, e.g. "METAR". See ProductCadence.
+
+	Airports         []AirportConfig // Non-towered fields to broadcast runway in use for, see AirportConfig.
+	ATISOverridePath string          // Operator runway in use overrides, see ATISOverride.
+}
+
+// METARSource is an extra set of METARs merged into the radius query results, e.g. Canadian stations
+// for sites near the border:
+//
+//	{"Name": "canada", "IdentPrefix": "C", "North": 49.0, "South": 41.5, "West": -95.0, "East": -74.0}
+//
+// Without a region (all bounds zero) stations are kept if within the METAR radius of the station.
+type METARSource struct {
+	Name        string // Used in stats.
+	IdentPrefix string // ADDS ident query, "C" = all Canadian stations.
+	North       float64
+	South       float64
+	West        float64
+	East        float64
+}
+
+// SchedulerConfig weights reports in the carousel. A report's weight is the share of passes it goes
+// out in, 1 = every pass:
+//
+//	weight = product weight * category weight / (1 + distance/DistanceScale) / (1 + age/AgeScale)
+//
+// clamped to MinWeight..1. Age only applies to METARs. Unchanged reports are further scaled by
+// MaintenanceWeight, see carousel.
+type SchedulerConfig struct {
+	METARWeight     float64
+	TAFWeight       float64
+	DistanceScale   float64            // Statute miles. 0 = distance doesn't matter.
+	AgeScale        float64            // Minutes. 0 = age doesn't matter.
+	CategoryWeights map[string]float64 // By flight category: "VFR", "MVFR", "IFR", "LIFR".
+	MinWeight       float64            // Floor, so that every report still goes out now and then.
+
+	BurstCount        int     // New and changed reports go out in this many consecutive passes.
+	MaintenanceWeight float64 // Multiplier for reports that haven't changed since their burst.
+}
+
+// ProductCadence sets how often a product is fetched and sent, e.g.
+//
+//	"Cadence": {"METAR": {"Repetitions": 5, "MaxAge": 90}, "WINDS_ALOFT": {"FetchInterval": 10800, "Cadence": 60}}
+//
+// Zero keeps the built-in behavior. Carousel products (METAR, TAF) are timed per report, the others
+// per pass over all of their reports. AIRMETs go with SIGMET, they are fetched together.
+type ProductCadence struct {
+	FetchInterval int // Seconds between refreshes of the product's source.
+	Cadence       int // Seconds between sends. For BEACON, between beacons.
+	Repetitions   int // New and changed carousel reports go out in this many consecutive passes. Overrides Scheduler.BurstCount.
+	MaxAge        int // Minutes since observation after which a report is no longer sent.
+}
+
+// FreqSchemeEntry is one slot of the hop schedule. Slots follow each other in order, each for Dwell
+// milliseconds, and the schedule repeats. Slot boundaries are aligned to GPS time. RX starts on its
+// own FreqScheme and then follows the schedule the first station it hears advertises in its beacon.
+type FreqSchemeEntry struct {
+	Freq    float64 // MHz.
+	Dwell   int     // Milliseconds.
+	ModMode byte
+}
+
+// RadioConfig is one of several TX dongles, e.g.
+//
+//	{"Name": "metars", "ProductID": 28713, "Freq": 915.0, "Products": ["METAR", "DERIVED"]}
+//
+// Products lists the message types (see WeatherMessage.Type) the radio carries. A radio without the
+// list carries everything not listed elsewhere.
+type RadioConfig struct {
+	Name         string
+	ProductID    int
+	Freq         float64
+	RadioModMode byte
+	FreqScheme   []FreqSchemeEntry // Hop schedule. Empty = stay on Freq.
+	Products     []string
+}
+
+// RunwayConfig is one runway end of an airport in the runway table, e.g.
+//
+//	{"Name": "27", "Heading": 270, "Pattern": "R", "Calm": true}
+//
+// Pattern is the traffic pattern direction, "L" or "R". Calm marks the preferred runway in calm or
+// variable wind.
+type RunwayConfig struct {
+	Name    string
+	Heading int // Degrees true.
+	Pattern string
+	Calm    bool
+}
+
+// AirportConfig is one non-towered field we broadcast runway in use for.
+type AirportConfig struct {
+	Ident   string
+	CTAF    string // e.g. "122.8".
+	Notes   string // Appended to every broadcast, e.g. "PPR FOR GLIDER OPS". Must fit in one packet with the rest, see atisNotesRoom().
+	Runways []RunwayConfig
 }
 
 var globalSettings settings
@@ -39,6 +184,7 @@ type MySituation struct {
 }
 
 var Location MySituation    // Station GPS data.
+var gpsClockOffset int64    // Nanoseconds of GPS time minus the system clock, as of the last fix. Atomic, the radios read it.
 var stationGeoPt *geo.Point // Station location.
 
 // Logging.
@@ -56,9 +202,8 @@ func setupLogging(fn string) {
 // Location.
 
 func situationUpdater() {
-	situationUpdateTicker := time.NewTicker(1 * time.Second)
 	for {
-		<-situationUpdateTicker.C
+		<-sysClock.After(1 * time.Second)
 
 		resp, err := http.Get(SITUATION_URL)
 		if err != nil {
@@ -93,17 +238,76 @@ func situationUpdater() {
 				log.Printf("First GPS location obtained.\n")
 			}
 			stationGeoPt = geo.NewPoint(float64(thisLocation.GPSLatitude), float64(thisLocation.GPSLongitude))
+			if !thisLocation.GPSTime.IsZero() {
+				atomic.StoreInt64(&gpsClockOffset, int64(thisLocation.GPSTime.Sub(sysClock.Now())))
+			}
 		}
 		Location = thisLocation
 	}
 }
 
+// Emergency authentication.
+
+const (
+	EMERGENCY_MAC_LEN = 8 // Bytes of the HMAC-SHA256 that are sent. Plenty against forging, and keeps the packet small.
+)
+
+// emergencyMAC authenticates the content of an emergency message with the shared EmergencyKey. TX time
+// is left out so that the same MAC is valid for every repeat.
+func emergencyMAC(key string, ident string, text string, validFrom uint32, validTo uint32) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%d", ident, text, validFrom, validTo)
+	return mac.Sum(nil)[:EMERGENCY_MAC_LEN]
+}
+
+func verifyEmergencyMAC(key string, ident string, text string, validFrom uint32, validTo uint32, sum []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	return hmac.Equal(sum, emergencyMAC(key, ident, text, validFrom, validTo))
+}
+
+// gpsNow is the current time by the GPS clock. Falls back to the system clock until the first fix.
+func gpsNow() time.Time {
+	return sysClock.Now().Add(time.Duration(atomic.LoadInt64(&gpsClockOffset)))
+}
+
 // Settings.
 
 func defaultSettings() {
 	globalSettings.Mode = MODE_TX
 	globalSettings.Freq = 915.00
+	globalSettings.BandPlan = "US"
+	globalSettings.TDMASlots = 4
+	globalSettings.TDMASlot = -1
+	globalSettings.TDMAGuard = 20
+	globalSettings.LBTProductID = 0x7028
+	globalSettings.LBTThreshold = -90
+	globalSettings.LBTMinBackoff = 5
+	globalSettings.LBTMaxBackoff = 50
+	globalSettings.LBTMaxDeferrals = 20
 	globalSettings.RadioModMode = 1
+	globalSettings.CoverageRadius = 150.0
+	globalSettings.NOTAMPath = "/boot/txwx_notams.json"
+	globalSettings.TFRPath = "/boot/txwx_tfrs.geojson"
+	globalSettings.BulletinSource = "/boot/txwx_bulletins.json"
+	globalSettings.RadarPath = "/boot/txwx_radar.png"
+	globalSettings.ATISOverridePath = "/boot/txwx_atis.json"
+	globalSettings.GAIRMETWeight = 8
+	globalSettings.Scheduler = SchedulerConfig{
+		METARWeight:     1.0,
+		TAFWeight:       0.5,
+		DistanceScale:   100.0,
+		AgeScale:        120.0,
+		CategoryWeights: map[string]float64{"VFR": 1.0, "MVFR": 1.5, "IFR": 2.0, "LIFR": 3.0},
+		MinWeight:       0.1,
+
+		BurstCount:        3,
+		MaintenanceWeight: 0.5,
+	}
+	globalSettings.EmergencyPath = "/boot/txwx_emergency.json"
+	globalSettings.EmergencyDuration = 60
+	globalSettings.EmergencyInterval = 2
 }
 
 func readSettings() {
@@ -115,15 +319,16 @@ func readSettings() {
 		return
 	}
 	defer fd.Close()
-	buf := make([]byte, 1024)
-	count, err := fd.Read(buf)
+	buf, err := ioutil.ReadAll(fd)
 	if err != nil {
 		log.Printf("can't read settings %s: %s\n", CONFIG_LOCATION, err.Error())
 		defaultSettings()
 		return
 	}
-	var newSettings settings
-	err = json.Unmarshal(buf[0:count], &newSettings)
+	// Start from the defaults so that settings missing from an older config file get sane values.
+	defaultSettings()
+	newSettings := globalSettings
+	err = json.Unmarshal(buf, &newSettings)
 	if err != nil {
 		log.Printf("can't read settings %s: %s\n", CONFIG_LOCATION, err.Error())
 		defaultSettings()
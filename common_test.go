@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resetSettings puts back the defaults, including settings defaultSettings() leaves alone.
+func resetSettings() {
+	globalSettings = settings{}
+	defaultSettings()
+}
+
+func TestEmergencyMAC(t *testing.T) {
+	mac := emergencyMAC("secret", "EMER1", "EVACUATE RWY 17", 1000, 2000)
+	if len(mac) != EMERGENCY_MAC_LEN {
+		t.Fatalf("MAC is %d bytes", len(mac))
+	}
+	if !verifyEmergencyMAC("secret", "EMER1", "EVACUATE RWY 17", 1000, 2000, mac) {
+		t.Errorf("good MAC rejected")
+	}
+	tests := []struct {
+		key, ident, text string
+		from, to         uint32
+	}{
+		{"other", "EMER1", "EVACUATE RWY 17", 1000, 2000},
+		{"secret", "EMER2", "EVACUATE RWY 17", 1000, 2000},
+		{"secret", "EMER1", "EVACUATE RWY 35", 1000, 2000},
+		{"secret", "EMER1", "EVACUATE RWY 17", 1000, 3000},
+		{"", "EMER1", "EVACUATE RWY 17", 1000, 2000},
+	}
+	for _, tt := range tests {
+		if verifyEmergencyMAC(tt.key, tt.ident, tt.text, tt.from, tt.to, mac) {
+			t.Errorf("MAC accepted for %+v", tt)
+		}
+	}
+}
+
+func TestGPSNow(t *testing.T) {
+	c := newFakeClock(time.Unix(6000, 0))
+	defer useClock(c)()
+	defer atomic.StoreInt64(&gpsClockOffset, 0)
+	if !gpsNow().Equal(c.Now()) {
+		t.Errorf("no fix yet, gpsNow() = %s", gpsNow())
+	}
+	atomic.StoreInt64(&gpsClockOffset, int64(1500*time.Millisecond))
+	if want := c.Now().Add(1500 * time.Millisecond); !gpsNow().Equal(want) {
+		t.Errorf("gpsNow() = %s, want %s", gpsNow(), want)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MAX_EMERGENCY_TEXT    = 80              // Leaves room for the MAC in one packet.
+	EMERGENCY_RELOAD_TIME = 5 * time.Second // The emergency file is watched more closely than the others.
+)
+
+// Emergency is an urgent notice from the operator's emergency file, e.g.
+//
+//	[{"Ident": "KXYZ", "Text": "AIRPORT CLOSED ACFT ACCIDENT RWY 09/27", "End": "2019-10-19T18:00:00Z"}]
+//
+// A missing Start means now (the file's modification time), a missing End means EmergencyDuration
+// minutes after Start.
+type Emergency struct {
+	Ident string
+	Text  string
+	Start time.Time
+	End   time.Time
+}
+
+func (e Emergency) Active(t time.Time) bool {
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+func loadEmergencies(fn string) ([]Emergency, error) {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var emergencies []Emergency
+	err = json.Unmarshal(buf, &emergencies)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Emergency
+	for _, e := range emergencies {
+		if len(e.Ident)+len(e.Text) > MAX_EMERGENCY_TEXT {
+			log.Printf("emergency %s is too long (%d characters max), skipping.\n", e.Ident, MAX_EMERGENCY_TEXT)
+			continue
+		}
+		if e.Start.IsZero() {
+			e.Start = fi.ModTime()
+		}
+		if e.End.IsZero() {
+			e.End = e.Start.Add(time.Duration(globalSettings.EmergencyDuration) * time.Minute)
+		}
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+func createEmergencyWeatherMessage(e Emergency) *txwx.WeatherMessage {
+	validFrom := uint32(e.Start.Unix())
+	validTo := uint32(e.End.Unix())
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_EMERGENCY,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: validFrom,
+		ValidFrom:       validFrom,
+		ValidTo:         validTo,
+		Ident:           e.Ident,
+		TextData:        e.Text,
+		Mac:             emergencyMAC(globalSettings.EmergencyKey, e.Ident, e.Text, validFrom, validTo),
+	}
+}
+
+// sendEmergencies sends the emergencies active at now, and reports whether any went out.
+func sendEmergencies(emergencies []Emergency, now time.Time, send func(*txwx.WeatherMessage) error) bool {
+	sent := false
+	for _, v := range emergencies {
+		if !v.Active(now) {
+			continue
+		}
+		err := send(createEmergencyWeatherMessage(v))
+		if err != nil {
+			log.Printf("emergency %s: %s\n", v.Ident, err.Error())
+			continue
+		}
+		atomic.AddUint64(&globalStatus.EmergenciesSent, 1)
+		sent = true
+	}
+	return sent
+}
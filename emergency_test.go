@@ -0,0 +1,170 @@
+package main
+
+import (
+	"./proto"
+	"hash/crc64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useEmergencies makes emergencies the loaded ones, returning a func that puts the old ones back.
+func useEmergencies(emergencies []Emergency) func() {
+	if lookupMutex == nil {
+		lookupMutex = &sync.Mutex{}
+	}
+	lookupMutex.Lock()
+	old := allEmergencies
+	allEmergencies = emergencies
+	lookupMutex.Unlock()
+	return func() {
+		lookupMutex.Lock()
+		allEmergencies = old
+		lookupMutex.Unlock()
+	}
+}
+
+func TestLoadEmergencies(t *testing.T) {
+	resetSettings()
+	dir, err := ioutil.TempDir("", "emergency")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "emergency.json")
+	long := strings.Repeat("X", MAX_EMERGENCY_TEXT)
+	buf := `[{"Ident": "EMER1", "Text": "AIRPORT CLOSED"},
+		{"Ident": "EMER2", "Text": "` + long + `"},
+		{"Ident": "EMER3", "Text": "RWY 09/27 CLSD", "Start": "2019-10-19T12:00:00Z"},
+		{"Ident": "EMER4", "Text": "EVACUATE", "Start": "2019-10-19T12:00:00Z", "End": "2019-10-19T12:30:00Z"}]`
+	if err := ioutil.WriteFile(fn, []byte(buf), 0644); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	mtime := time.Date(2019, 10, 19, 15, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(fn, mtime, mtime); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	emergencies, err := loadEmergencies(fn)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	start := time.Date(2019, 10, 19, 12, 0, 0, 0, time.UTC)
+	duration := time.Duration(globalSettings.EmergencyDuration) * time.Minute
+	want := []Emergency{
+		{Ident: "EMER1", Start: mtime, End: mtime.Add(duration)},
+		{Ident: "EMER3", Start: start, End: start.Add(duration)},
+		{Ident: "EMER4", Start: start, End: start.Add(30 * time.Minute)},
+	}
+	if len(emergencies) != len(want) {
+		t.Fatalf("loaded %d emergencies, want %d", len(emergencies), len(want))
+	}
+	for i, w := range want {
+		e := emergencies[i]
+		if e.Ident != w.Ident || !e.Start.Equal(w.Start) || !e.End.Equal(w.End) {
+			t.Errorf("%s: %s - %s, want %s %s - %s", e.Ident, e.Start, e.End, w.Ident, w.Start, w.End)
+		}
+	}
+
+	if err := ioutil.WriteFile(fn, []byte(`[{"Ident": `), 0644); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if _, err := loadEmergencies(fn); err == nil {
+		t.Errorf("bad JSON loaded")
+	}
+	if _, err := loadEmergencies(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
+
+func TestSendEmergencies(t *testing.T) {
+	resetSettings()
+	globalSettings.EmergencyKey = "secret"
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	emergencies := []Emergency{
+		{Ident: "EMER1", Text: "OVER", Start: time.Unix(1000, 0), End: time.Unix(2000, 0)},
+		{Ident: "EMER2", Text: "EVACUATE RWY 17", Start: time.Unix(4000, 0), End: time.Unix(6000, 0)},
+		{Ident: "EMER3", Text: "NOT YET", Start: time.Unix(8000, 0), End: time.Unix(9000, 0)},
+	}
+	var sent []*txwx.WeatherMessage
+	send := func(msg *txwx.WeatherMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}
+	before := atomic.LoadUint64(&globalStatus.EmergenciesSent)
+	if !sendEmergencies(emergencies, c.Now(), send) {
+		t.Errorf("active emergency not reported as sent")
+	}
+	if len(sent) != 1 || sent[0].Ident != "EMER2" {
+		t.Fatalf("sent %v, want EMER2 only", sent)
+	}
+	m := sent[0]
+	if m.Type != txwx.WeatherMessage_EMERGENCY || m.ValidFrom != 4000 || m.ValidTo != 6000 {
+		t.Errorf("sent %v", m)
+	}
+	if !verifyEmergencyMAC("secret", m.Ident, m.TextData, m.ValidFrom, m.ValidTo, m.Mac) {
+		t.Errorf("MAC doesn't verify")
+	}
+	if n := atomic.LoadUint64(&globalStatus.EmergenciesSent) - before; n != 1 {
+		t.Errorf("EmergenciesSent went up by %d", n)
+	}
+	sent = nil
+	if sendEmergencies(emergencies, time.Unix(7000, 0), send) || len(sent) != 0 {
+		t.Errorf("sent %d emergencies with none active", len(sent))
+	}
+}
+
+func TestEmergencyPreemptsCarousel(t *testing.T) {
+	resetSettings()
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	r := testRadio(nil)
+
+	// Queued before the emergency started.
+	r.queue.push(testPacket(txwx.WeatherMessage_METAR, 1), PRIORITY_CAROUSEL)
+	r.queue.push(testPacket(txwx.WeatherMessage_BEACON, 2), PRIORITY_BEACON)
+	defer useEmergencies([]Emergency{{Ident: "EMER1", Text: "EVACUATE RWY 17", Start: time.Unix(4000, 0), End: time.Unix(6000, 0)}})()
+	before := atomic.LoadUint64(&globalStatus.MessagesPreempted)
+
+	// Queued during the emergency.
+	metar := &txwx.WeatherMessage{Type: txwx.WeatherMessage_METAR, TxTime: 5000, Ident: "KXYZ", TextData: "METAR KXYZ"}
+	if err := txWeatherMessageOn([]*txRadio{r}, metar); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if err := txCachedPacket(r, newPacketCache().lookup("METAR KXYZ", "1", func() *txwx.WeatherMessage { return metar })); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if n := atomic.LoadUint64(&globalStatus.MessagesPreempted) - before; n != 2 {
+		t.Errorf("%d new carousel messages preempted, want 2", n)
+	}
+
+	go r.run()
+	idle := make(chan bool)
+	go func() {
+		r.queue.waitIdle()
+		idle <- true
+	}()
+	for done := false; !done; {
+		select {
+		case <-idle:
+			done = true
+		case <-time.After(10 * time.Millisecond):
+			c.advance(10 * time.Millisecond)
+			if c.Now().After(time.Unix(5010, 0)) {
+				t.Fatalf("queue never drained")
+			}
+		}
+	}
+	if n := atomic.LoadUint64(&r.MessagesSent); n != 1 {
+		t.Errorf("%d packets sent, want the beacon only", n)
+	}
+	if n := atomic.LoadUint64(&globalStatus.MessagesPreempted) - before; n != 3 {
+		t.Errorf("%d carousel messages preempted, want 3", n)
+	}
+}
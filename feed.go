@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	FEED_RETRY_TIME = 15 * time.Second
+)
+
+// readSource reads a local file, or the body of an http(s) URL.
+func readSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return ioutil.ReadFile(source)
+	}
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(source + ": " + resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func readFeedLines(r io.Reader, handle func([]byte)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 {
+			handle([]byte(line))
+		}
+	}
+	return scanner.Err()
+}
+
+// tailFeedFile follows a file as it is appended to, like "tail -f".
+func tailFeedFile(fn string, handle func([]byte)) error {
+	fp, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	rd := bufio.NewReader(fp)
+	partial := ""
+	for {
+		line, err := rd.ReadString('\n')
+		partial += line
+		if err == io.EOF {
+			sysClock.Sleep(1 * time.Second)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if s := strings.TrimSpace(partial); len(s) > 0 {
+			handle([]byte(s))
+		}
+		partial = ""
+	}
+}
+
+func listenFeedUDP(port string, handle func([]byte)) error {
+	conn, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		handle(buf[:n])
+	}
+}
+
+// readFeed reads a local JSON feed forever, passing each message to handle. Sources are:
+//
+//	udp:50222              One JSON message per datagram (e.g. WeatherFlow broadcasts).
+//	serial:/dev/ttyUSB0    JSON lines from a serial port. Line settings must be set beforehand (stty).
+//	unix:/run/sensor.sock  JSON lines from a local socket.
+//	file:/tmp/sensor.log   JSON lines appended to a file.
+func readFeed(name string, source string, handle func([]byte)) {
+	for {
+		var err error
+		parts := strings.SplitN(source, ":", 2)
+		if len(parts) != 2 {
+			log.Printf("%s: bad source %s, ignoring.\n", name, source)
+			return
+		}
+		switch parts[0] {
+		case "udp":
+			err = listenFeedUDP(parts[1], handle)
+		case "serial":
+			var fp *os.File
+			fp, err = os.Open(parts[1])
+			if err == nil {
+				err = readFeedLines(fp, handle)
+				fp.Close()
+			}
+		case "unix":
+			var conn net.Conn
+			conn, err = net.Dial("unix", parts[1])
+			if err == nil {
+				err = readFeedLines(conn, handle)
+				conn.Close()
+			}
+		case "file":
+			err = tailFeedFile(parts[1], handle)
+		default:
+			log.Printf("%s: unknown source type %s, ignoring.\n", name, parts[0])
+			return
+		}
+		if err != nil {
+			log.Printf("%s %s: %s\n", name, source, err.Error())
+		}
+		sysClock.Sleep(FEED_RETRY_TIME)
+	}
+}
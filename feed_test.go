@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txwx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "source.json")
+	if err := ioutil.WriteFile(fn, []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("from http"))
+	}))
+	defer srv.Close()
+	tests := []struct {
+		source string
+		want   string
+		ok     bool
+	}{
+		{fn, "from file", true},
+		{filepath.Join(dir, "missing.json"), "", false},
+		{srv.URL + "/ok", "from http", true},
+		{srv.URL + "/missing", "", false},
+	}
+	for _, tt := range tests {
+		buf, err := readSource(tt.source)
+		if (err == nil) != tt.ok || string(buf) != tt.want {
+			t.Errorf("readSource(%s) = %q, %v", tt.source, buf, err)
+		}
+	}
+}
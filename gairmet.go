@@ -0,0 +1,100 @@
+package main
+
+import (
+	"./proto"
+	"encoding/xml"
+	"fmt"
+	"github.com/kellydunn/golang-geo"
+	"time"
+)
+
+const (
+	GAIRMET_URL = "https://aviationweather.gov/api/data/gairmet?format=xml"
+)
+
+// GAIRMET mirrors a <GAIRMET> element of the aviationweather.gov XML feed. Each one is a snapshot of
+// one hazard area at valid_time (forecast hours 0, 3, 6, ...).
+type GAIRMET struct {
+	IssueTime    time.Time `xml:"issue_time"`
+	ExpireTime   time.Time `xml:"expire_time"`
+	ValidTime    time.Time `xml:"valid_time"`
+	Product      string    `xml:"product"` // SIERRA, TANGO or ZULU.
+	Tag          string    `xml:"tag"`
+	ForecastHour int       `xml:"forecast_hour"`
+	Hazard       struct {
+		Type     string `xml:"type,attr"`
+		Severity string `xml:"severity,attr"`
+	} `xml:"hazard"`
+	Altitude struct {
+		MinFtMSL int `xml:"min_ft_msl,attr"`
+		MaxFtMSL int `xml:"max_ft_msl,attr"`
+	} `xml:"altitude"`
+	feedArea
+}
+
+type gairmetResponse struct {
+	GAIRMETs []GAIRMET `xml:"data>GAIRMET"`
+}
+
+var gairmetHazards = map[string]txwx.Hazard{
+	"IFR":     txwx.Hazard_IFR,
+	"MT_OBSC": txwx.Hazard_MTN_OBSCN,
+	"TURB-HI": txwx.Hazard_TURBULENCE,
+	"TURB-LO": txwx.Hazard_TURBULENCE,
+	"ICE":     txwx.Hazard_ICING,
+	"LLWS":    txwx.Hazard_LLWS,
+	"SFC_WND": txwx.Hazard_SFC_WIND,
+}
+
+// Ident names the snapshot like the text products do, e.g. "TANGO 2E +3".
+func (g GAIRMET) Ident() string {
+	return fmt.Sprintf("%s %s +%d", g.Product, g.Tag, g.ForecastHour)
+}
+
+// getGAIRMETsInRadiusOf reads G-AIRMETs from the ADDS feed, or from a local file or other URL in the
+// same XML format, and keeps those overlapping the circle of radius statute miles around p. Polygons
+// are clipped to that circle, so only the part we cover goes on the air.
+func getGAIRMETsInRadiusOf(source string, radius float64, p *geo.Point) ([]GAIRMET, error) {
+	body, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	var r gairmetResponse
+	err = xml.Unmarshal(body, &r)
+	if err != nil {
+		return nil, err
+	}
+	var ret []GAIRMET
+	for _, g := range r.GAIRMETs {
+		clipped := clipPolygonToCircle(g.Polygon(), p, radius*KM_PER_SM)
+		if len(clipped) < 3 {
+			continue
+		}
+		g.Points = nil
+		for _, v := range clipped {
+			g.Points = append(g.Points, feedPoint{v.Lat(), v.Lng()})
+		}
+		ret = append(ret, g)
+	}
+	return ret, nil
+}
+
+func createGAIRMETWeatherMessage(g GAIRMET) *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_GAIRMET,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(g.IssueTime.Unix()),
+		ValidFrom:       uint32(g.ValidTime.Unix()),
+		ValidTo:         uint32(g.ExpireTime.Unix()),
+		Ident:           g.Ident(),
+		Hazard:          gairmetHazards[g.Hazard.Type],
+		Severity:        airsigmetSeverities[g.Hazard.Severity],
+		Area: &txwx.Area{
+			Polygon: encodePolygon(reducePolygon(g.Polygon(), MAX_POLYGON_VERTICES)),
+			Floor:   uint32(g.Altitude.MinFtMSL / 100),
+			Ceiling: uint32(g.Altitude.MaxFtMSL / 100),
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"testing"
+)
+
+func TestGAIRMETAreaContainsHazard(t *testing.T) {
+	var g GAIRMET
+	star := circlePoints(geo.NewPoint(44.25, -81.60), 30, 40, true) // Concave.
+	for _, p := range star {
+		g.Points = append(g.Points, feedPoint{Latitude: p.Lat(), Longitude: p.Lng()})
+	}
+	sent, err := decodePolygon(createGAIRMETWeatherMessage(g).Area.Polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) > MAX_POLYGON_VERTICES {
+		t.Errorf("%d vertices sent", len(sent))
+	}
+	for i, p := range star {
+		if !insideConvex(sent, p) {
+			t.Errorf("vertex %d %s not inside the sent area", i, formatLatLng(p))
+		}
+	}
+}
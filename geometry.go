@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/kellydunn/golang-geo"
+	"math"
+	"sort"
+)
+
+const (
+	POLYGON_SCALE        = 100.0 // Vertices are encoded in 1/100 degree (~1 km) steps.
+	CLIP_CIRCLE_SIDES    = 16    // Sides of the polygon standing in for the coverage circle when clipping.
+	MAX_POLYGON_VERTICES = 16    // Larger polygons are reduced to fit in one packet.
+
+	KM_PER_SM        = 1.609344
+	KM_PER_NM        = 1.852
+	KM_PER_DEG_LAT   = 110.574
+	KM_PER_DEG_EQUAT = 111.320
+)
+
+// encodePolygon packs a list of vertices as zigzag varints. The first vertex is absolute, each
+// following vertex is the delta from the previous one, so small polygons take 2-4 bytes per vertex.
+func encodePolygon(points []*geo.Point) []byte {
+	buf := make([]byte, 0, len(points)*4)
+	tmp := make([]byte, binary.MaxVarintLen64)
+	var lastLat, lastLng int64
+	for _, p := range points {
+		lat := int64(math.Floor(p.Lat()*POLYGON_SCALE + 0.5))
+		lng := int64(math.Floor(p.Lng()*POLYGON_SCALE + 0.5))
+		n := binary.PutVarint(tmp, lat-lastLat)
+		buf = append(buf, tmp[:n]...)
+		n = binary.PutVarint(tmp, lng-lastLng)
+		buf = append(buf, tmp[:n]...)
+		lastLat, lastLng = lat, lng
+	}
+	return buf
+}
+
+func decodePolygon(data []byte) ([]*geo.Point, error) {
+	var points []*geo.Point
+	var lat, lng int64
+	for len(data) > 0 {
+		dLat, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, errors.New("decodePolygon(): bad latitude delta.")
+		}
+		data = data[n:]
+		dLng, n := binary.Varint(data)
+		if n <= 0 {
+			return nil, errors.New("decodePolygon(): bad longitude delta.")
+		}
+		data = data[n:]
+		lat += dLat
+		lng += dLng
+		points = append(points, geo.NewPoint(float64(lat)/POLYGON_SCALE, float64(lng)/POLYGON_SCALE))
+	}
+	return points, nil
+}
+
+// reducePolygon returns at most max vertices outlining an area that contains all of points, for areas
+// that must not shrink when sent (TFRs, hazard areas). It takes the convex hull, then repeatedly drops the edge whose
+// removal adds the least area by extending its neighbouring edges until they meet. Finally every edge is
+// moved out by the rounding error of encodePolygon.
+func reducePolygon(points []*geo.Point, max int) []*geo.Point {
+	if len(points) <= max || max < 3 {
+		return points
+	}
+	center := points[0]
+	xs := make([][2]float64, 0, len(points))
+	for _, p := range points {
+		x, y := localXY(center, p)
+		xs = append(xs, [2]float64{x, y})
+	}
+	hull := convexHull(xs)
+	for len(hull) > max {
+		n := len(hull)
+		best, bestArea := -1, math.Inf(1)
+		var bestPt [2]float64
+		for i := range hull {
+			a, b, c, d := hull[(i+n-1)%n], hull[i], hull[(i+1)%n], hull[(i+2)%n]
+			p, ok := lineIntersection(a, b, d, c)
+			// The extended edges must meet beyond b and c, not behind them.
+			if !ok || (p[0]-b[0])*(b[0]-a[0])+(p[1]-b[1])*(b[1]-a[1]) <= 0 ||
+				(p[0]-c[0])*(c[0]-d[0])+(p[1]-c[1])*(c[1]-d[1]) <= 0 {
+				continue
+			}
+			area := math.Abs(cross(b, c, p)) / 2
+			if area < bestArea {
+				best, bestArea, bestPt = i, area, p
+			}
+		}
+		if best < 0 {
+			break
+		}
+		hull[best] = bestPt
+		hull = append(hull[:(best+1)%n], hull[(best+1)%n+1:]...)
+	}
+	// Half a step in each direction.
+	margin := math.Hypot(0.5/POLYGON_SCALE*KM_PER_DEG_LAT, 0.5/POLYGON_SCALE*KM_PER_DEG_EQUAT*math.Cos(center.Lat()*math.Pi/180.0))
+	hull = offsetPolygon(hull, margin)
+	ret := make([]*geo.Point, 0, len(hull))
+	for _, p := range hull {
+		ret = append(ret, fromLocalXY(center, p[0], p[1]))
+	}
+	return ret
+}
+
+// cross is the z component of (b-a) x (c-a), positive if a, b, c turn counterclockwise.
+func cross(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// convexHull returns the hull of xs counterclockwise, without collinear points (monotone chain).
+func convexHull(xs [][2]float64) [][2]float64 {
+	pts := make([][2]float64, len(xs))
+	copy(pts, xs)
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i][0] < pts[j][0] || (pts[i][0] == pts[j][0] && pts[i][1] < pts[j][1])
+	})
+	if len(pts) < 3 {
+		return pts
+	}
+	hull := make([][2]float64, 0, 2*len(pts))
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], pts[i]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, pts[i])
+	}
+	return hull[:len(hull)-1]
+}
+
+// lineIntersection intersects the line through a and b with the line through c and d.
+func lineIntersection(a, b, c, d [2]float64) ([2]float64, bool) {
+	dx1, dy1 := b[0]-a[0], b[1]-a[1]
+	dx2, dy2 := d[0]-c[0], d[1]-c[1]
+	den := dx1*dy2 - dy1*dx2
+	if math.Abs(den) < 1e-12 {
+		return [2]float64{}, false
+	}
+	t := ((c[0]-a[0])*dy2 - (c[1]-a[1])*dx2) / den
+	return [2]float64{a[0] + t*dx1, a[1] + t*dy1}, true
+}
+
+// offsetPolygon moves every edge of a counterclockwise convex polygon out by dist.
+func offsetPolygon(xs [][2]float64, dist float64) [][2]float64 {
+	n := len(xs)
+	if n < 3 {
+		return xs
+	}
+	shifted := func(i int) ([2]float64, [2]float64) {
+		a, b := xs[i], xs[(i+1)%n]
+		l := math.Hypot(b[0]-a[0], b[1]-a[1])
+		nx, ny := (b[1]-a[1])/l*dist, -(b[0]-a[0])/l*dist
+		return [2]float64{a[0] + nx, a[1] + ny}, [2]float64{b[0] + nx, b[1] + ny}
+	}
+	ret := make([][2]float64, 0, n)
+	for i := range xs {
+		a, b := shifted((i + n - 1) % n)
+		c, d := shifted(i)
+		p, ok := lineIntersection(a, b, c, d)
+		if !ok {
+			p = b
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// localXY projects p onto a flat plane centered at center, in km. Good enough for the few hundred
+// km we care about around a station.
+func localXY(center, p *geo.Point) (float64, float64) {
+	x := (p.Lng() - center.Lng()) * KM_PER_DEG_EQUAT * math.Cos(center.Lat()*math.Pi/180.0)
+	y := (p.Lat() - center.Lat()) * KM_PER_DEG_LAT
+	return x, y
+}
+
+// fromLocalXY is the inverse of localXY.
+func fromLocalXY(center *geo.Point, x, y float64) *geo.Point {
+	lat := center.Lat() + y/KM_PER_DEG_LAT
+	lng := center.Lng() + x/(KM_PER_DEG_EQUAT*math.Cos(center.Lat()*math.Pi/180.0))
+	return geo.NewPoint(lat, lng)
+}
+
+// segmentDistance returns the distance from the origin to the segment (x1,y1)-(x2,y2).
+func segmentDistance(x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	lenSq := dx*dx + dy*dy
+	t := 0.0
+	if lenSq > 0 {
+		t = math.Max(0, math.Min(1, -(x1*dx+y1*dy)/lenSq))
+	}
+	return math.Hypot(x1+t*dx, y1+t*dy)
+}
+
+// polygonIntersectsCircle checks if the polygon overlaps the circle of radiusKm around center. That is
+// the case when the center is inside the polygon, or any edge comes within radiusKm of the center.
+func polygonIntersectsCircle(points []*geo.Point, center *geo.Point, radiusKm float64) bool {
+	if len(points) == 0 || center == nil {
+		return false
+	}
+	if len(points) == 1 {
+		return center.GreatCircleDistance(points[0]) <= radiusKm
+	}
+	inside := false
+	for i := range points {
+		x1, y1 := localXY(center, points[i])
+		x2, y2 := localXY(center, points[(i+1)%len(points)])
+		if segmentDistance(x1, y1, x2, y2) <= radiusKm {
+			return true
+		}
+		// Ray cast along +x from the center.
+		if (y1 > 0) != (y2 > 0) && x1+(0-y1)*(x2-x1)/(y2-y1) > 0 {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// clipPolygonToCircle cuts the polygon down to the part inside the circle of radiusKm around center
+// (Sutherland-Hodgman). The circle is approximated by a circumscribed CLIP_CIRCLE_SIDES-gon, so nothing
+// inside the circle is lost. Returns nil if nothing is left.
+func clipPolygonToCircle(points []*geo.Point, center *geo.Point, radiusKm float64) []*geo.Point {
+	if len(points) < 3 || center == nil {
+		return points
+	}
+	xs := make([][2]float64, 0, len(points))
+	for _, p := range points {
+		x, y := localXY(center, p)
+		xs = append(xs, [2]float64{x, y})
+	}
+	// Edges of the clip polygon, counterclockwise.
+	r := radiusKm / math.Cos(math.Pi/CLIP_CIRCLE_SIDES)
+	for i := 0; i < CLIP_CIRCLE_SIDES && len(xs) > 0; i++ {
+		a1 := 2 * math.Pi * float64(i) / CLIP_CIRCLE_SIDES
+		a2 := 2 * math.Pi * float64(i+1) / CLIP_CIRCLE_SIDES
+		ex1, ey1 := r*math.Cos(a1), r*math.Sin(a1)
+		ex2, ey2 := r*math.Cos(a2), r*math.Sin(a2)
+		inside := func(p [2]float64) bool {
+			return (ex2-ex1)*(p[1]-ey1)-(ey2-ey1)*(p[0]-ex1) >= 0
+		}
+		intersect := func(p, q [2]float64) [2]float64 {
+			// Solve for the point on p-q that lies on the edge line.
+			dx, dy := q[0]-p[0], q[1]-p[1]
+			ndx, ndy := ex2-ex1, ey2-ey1
+			t := (ndx*(p[1]-ey1) - ndy*(p[0]-ex1)) / (ndy*dx - ndx*dy)
+			return [2]float64{p[0] + t*dx, p[1] + t*dy}
+		}
+		in := xs
+		xs = nil
+		for j := range in {
+			cur, prev := in[j], in[(j+len(in)-1)%len(in)]
+			if inside(cur) {
+				if !inside(prev) {
+					xs = append(xs, intersect(prev, cur))
+				}
+				xs = append(xs, cur)
+			} else if inside(prev) {
+				xs = append(xs, intersect(prev, cur))
+			}
+		}
+	}
+	if len(xs) < 3 {
+		return nil
+	}
+	ret := make([]*geo.Point, 0, len(xs))
+	for _, p := range xs {
+		ret = append(ret, fromLocalXY(center, p[0], p[1]))
+	}
+	return ret
+}
+
+// formatLatLng formats a point the way it appears in AIRMET/SIGMET text, e.g. "4425N08136W".
+func formatLatLng(p *geo.Point) string {
+	ns, ew := "N", "E"
+	lat, lng := p.Lat(), p.Lng()
+	if lat < 0 {
+		ns = "S"
+		lat = -lat
+	}
+	if lng < 0 {
+		ew = "W"
+		lng = -lng
+	}
+	latMin := int(math.Floor(lat*60 + 0.5))
+	lngMin := int(math.Floor(lng*60 + 0.5))
+	return fmt.Sprintf("%02d%02d%s%03d%02d%s", latMin/60, latMin%60, ns, lngMin/60, lngMin%60, ew)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"math"
+	"testing"
+)
+
+// circlePoints returns n vertices on a circle of radiusKm around center, counterclockwise, optionally
+// with every other vertex pulled in to make the outline concave.
+func circlePoints(center *geo.Point, radiusKm float64, n int, star bool) []*geo.Point {
+	var ret []*geo.Point
+	for i := 0; i < n; i++ {
+		r := radiusKm
+		if star && i%2 == 1 {
+			r /= 2
+		}
+		a := 2 * math.Pi * float64(i) / float64(n)
+		ret = append(ret, fromLocalXY(center, r*math.Cos(a), r*math.Sin(a)))
+	}
+	return ret
+}
+
+// insideConvex checks that p is strictly inside the counterclockwise convex polygon.
+func insideConvex(points []*geo.Point, p *geo.Point) bool {
+	for i := range points {
+		x1, y1 := localXY(p, points[i])
+		x2, y2 := localXY(p, points[(i+1)%len(points)])
+		if x1*y2-y1*x2 <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReducePolygonContainsOriginal(t *testing.T) {
+	center := geo.NewPoint(44.25, -81.60)
+	tests := []struct {
+		name   string
+		points []*geo.Point
+	}{
+		{"circle", circlePoints(center, 30, 40, false)},
+		{"star", circlePoints(center, 30, 40, true)},
+		{"small circle", circlePoints(center, 3, 64, false)},
+		{"long thin", []*geo.Point{
+			geo.NewPoint(44.00, -82.00), geo.NewPoint(44.01, -81.80), geo.NewPoint(44.02, -81.60),
+			geo.NewPoint(44.03, -81.40), geo.NewPoint(44.04, -81.20), geo.NewPoint(44.05, -81.00),
+			geo.NewPoint(44.10, -81.00), geo.NewPoint(44.09, -81.20), geo.NewPoint(44.08, -81.40),
+			geo.NewPoint(44.07, -81.60), geo.NewPoint(44.06, -81.80), geo.NewPoint(44.05, -82.00),
+			geo.NewPoint(44.04, -82.01), geo.NewPoint(44.03, -82.02), geo.NewPoint(44.02, -82.03),
+			geo.NewPoint(44.01, -82.02), geo.NewPoint(44.00, -82.01),
+		}},
+	}
+	for _, tt := range tests {
+		reduced := reducePolygon(tt.points, MAX_POLYGON_VERTICES)
+		if len(reduced) > MAX_POLYGON_VERTICES || len(reduced) < 3 {
+			t.Errorf("%s: %d vertices", tt.name, len(reduced))
+			continue
+		}
+		// What the receiver gets, after rounding.
+		sent, err := decodePolygon(encodePolygon(reduced))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err.Error())
+		}
+		for i, p := range tt.points {
+			if !insideConvex(sent, p) {
+				t.Errorf("%s: vertex %d %s not inside the sent polygon", tt.name, i, formatLatLng(p))
+			}
+		}
+	}
+}
+
+func TestReducePolygonKeepsSmallPolygons(t *testing.T) {
+	points := circlePoints(geo.NewPoint(44.25, -81.60), 30, MAX_POLYGON_VERTICES, true)
+	if reduced := reducePolygon(points, MAX_POLYGON_VERTICES); len(reduced) != len(points) || reduced[3] != points[3] {
+		t.Errorf("polygon within the limit changed")
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	xs := [][2]float64{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0}, {1, 2}}
+	hull := convexHull(xs)
+	want := [][2]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	if len(hull) != len(want) {
+		t.Fatalf("hull %v, want %v", hull, want)
+	}
+	for i := range want {
+		if hull[i] != want[i] {
+			t.Fatalf("hull %v, want %v", hull, want)
+		}
+	}
+}
+
+func TestPolygonCodec(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []*geo.Point
+	}{
+		{"empty", nil},
+		{"point", []*geo.Point{geo.NewPoint(44.25, -81.60)}},
+		{"triangle", []*geo.Point{geo.NewPoint(44.00, -82.00), geo.NewPoint(44.00, -81.00), geo.NewPoint(44.50, -81.50)}},
+		{"southern hemisphere", []*geo.Point{geo.NewPoint(-33.95, 151.18), geo.NewPoint(-34.10, 151.30), geo.NewPoint(-33.80, 151.40)}},
+		{"dateline", []*geo.Point{geo.NewPoint(51.88, 179.90), geo.NewPoint(51.88, -179.90), geo.NewPoint(52.00, 180.00)}},
+	}
+	for _, tt := range tests {
+		enc := encodePolygon(tt.points)
+		dec, err := decodePolygon(enc)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err.Error())
+			continue
+		}
+		if len(dec) != len(tt.points) {
+			t.Errorf("%s: %d vertices decoded, want %d", tt.name, len(dec), len(tt.points))
+			continue
+		}
+		for i, p := range tt.points {
+			if math.Abs(dec[i].Lat()-p.Lat()) > 0.5/POLYGON_SCALE || math.Abs(dec[i].Lng()-p.Lng()) > 0.5/POLYGON_SCALE {
+				t.Errorf("%s: vertex %d decoded as %s, want %s", tt.name, i, formatLatLng(dec[i]), formatLatLng(p))
+			}
+		}
+	}
+	if len(encodePolygon(circlePoints(geo.NewPoint(44.25, -81.60), 30, 16, false))) > 16*4 {
+		t.Errorf("small polygon takes more than 4 bytes per vertex")
+	}
+	if _, err := decodePolygon([]byte{0x80}); err == nil {
+		t.Errorf("truncated varint decoded")
+	}
+}
+
+func TestPolygonIntersectsCircle(t *testing.T) {
+	square := []*geo.Point{geo.NewPoint(44.0, -82.0), geo.NewPoint(44.0, -81.0), geo.NewPoint(45.0, -81.0), geo.NewPoint(45.0, -82.0)}
+	tests := []struct {
+		name     string
+		center   *geo.Point
+		radiusKm float64
+		want     bool
+	}{
+		{"center inside", geo.NewPoint(44.5, -81.5), 1, true},
+		{"edge within radius", geo.NewPoint(44.5, -80.9), 10, true},
+		{"outside", geo.NewPoint(44.5, -80.5), 10, false},
+		{"beyond a corner", geo.NewPoint(45.1, -80.9), 10, false},
+		{"no center", nil, 10, false},
+	}
+	for _, tt := range tests {
+		if got := polygonIntersectsCircle(square, tt.center, tt.radiusKm); got != tt.want {
+			t.Errorf("%s: %t", tt.name, got)
+		}
+	}
+}
+
+func TestClipPolygonToCircle(t *testing.T) {
+	center := geo.NewPoint(44.25, -81.60)
+	big := circlePoints(center, 300, 8, false)
+	clipped := clipPolygonToCircle(big, center, 50)
+	if len(clipped) != CLIP_CIRCLE_SIDES {
+		t.Fatalf("clipped to %d vertices, want the %d-gon", len(clipped), CLIP_CIRCLE_SIDES)
+	}
+	for i, p := range clipped {
+		if d := center.GreatCircleDistance(p); d < 50 || d > 50/math.Cos(math.Pi/CLIP_CIRCLE_SIDES)+0.5 {
+			t.Errorf("vertex %d is %0.1f km out", i, d)
+		}
+	}
+	small := circlePoints(center, 10, 8, false)
+	if got := clipPolygonToCircle(small, center, 50); len(got) != len(small) {
+		t.Errorf("polygon inside the circle clipped to %d vertices", len(got))
+	}
+	if got := clipPolygonToCircle(circlePoints(geo.NewPoint(47.0, -81.60), 20, 8, false), center, 50); got != nil {
+		t.Errorf("polygon outside the circle clipped to %d vertices", len(got))
+	}
+}
+
+func TestFormatLatLng(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		want     string
+	}{
+		{44.25, -81.60, "4415N08136W"},
+		{-33.95, 151.18, "3357S15111E"},
+		{0, 0, "0000N00000E"},
+		{44.9999, -81.9999, "4500N08200W"},
+	}
+	for _, tt := range tests {
+		if got := formatLatLng(geo.NewPoint(tt.lat, tt.lng)); got != tt.want {
+			t.Errorf("formatLatLng(%g, %g) = %s, want %s", tt.lat, tt.lng, got, tt.want)
+		}
+	}
+}
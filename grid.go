@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"github.com/kellydunn/golang-geo"
+	"math"
+)
+
+const (
+	GRID_MAX_VALUE = 15 // Cells hold 4-bit values.
+	MAX_GRID_BYTES = 96 // Encoded tiles larger than this are split so they fit in one packet.
+)
+
+// gridTile is a rectangular piece of a grid. Grids are laid out in the station's local plane: cell
+// (0, 0) is centered on the station, columns increase to the east and rows to the south.
+type gridTile struct {
+	ColOffset int // Column of the tile's top left cell.
+	RowOffset int // Row of the tile's top left cell.
+	Cols      int
+	Rows      int
+	Cells     []byte // Row major, one value per cell.
+}
+
+// encodeGridRLE packs 4-bit cell values as runs, one byte per run: (length-1)<<4 | value.
+func encodeGridRLE(cells []byte) []byte {
+	var buf []byte
+	for i := 0; i < len(cells); {
+		v := cells[i]
+		if v > GRID_MAX_VALUE {
+			v = GRID_MAX_VALUE
+		}
+		run := 1
+		for i+run < len(cells) && run < 16 && cells[i+run] == cells[i] {
+			run++
+		}
+		buf = append(buf, byte(run-1)<<4|v)
+		i += run
+	}
+	return buf
+}
+
+func decodeGridRLE(data []byte, n int) ([]byte, error) {
+	cells := make([]byte, 0, n)
+	for _, b := range data {
+		run := int(b>>4) + 1
+		for j := 0; j < run; j++ {
+			cells = append(cells, b&0x0f)
+		}
+	}
+	if len(cells) != n {
+		return nil, errors.New("decodeGridRLE(): cell count mismatch.")
+	}
+	return cells, nil
+}
+
+// splitGridTiles cuts t into tiles whose encoded size fits in MAX_GRID_BYTES, halving along the longer
+// side as needed.
+func splitGridTiles(t gridTile) []gridTile {
+	if len(encodeGridRLE(t.Cells)) <= MAX_GRID_BYTES || len(t.Cells) <= 1 {
+		return []gridTile{t}
+	}
+	var a, b gridTile
+	if t.Rows >= t.Cols {
+		half := t.Rows / 2
+		a = gridTile{t.ColOffset, t.RowOffset, t.Cols, half, t.Cells[:half*t.Cols]}
+		b = gridTile{t.ColOffset, t.RowOffset + half, t.Cols, t.Rows - half, t.Cells[half*t.Cols:]}
+	} else {
+		half := t.Cols / 2
+		a = gridTile{t.ColOffset, t.RowOffset, half, t.Rows, nil}
+		b = gridTile{t.ColOffset + half, t.RowOffset, t.Cols - half, t.Rows, nil}
+		for r := 0; r < t.Rows; r++ {
+			a.Cells = append(a.Cells, t.Cells[r*t.Cols:r*t.Cols+half]...)
+			b.Cells = append(b.Cells, t.Cells[r*t.Cols+half:(r+1)*t.Cols]...)
+		}
+	}
+	return append(splitGridTiles(a), splitGridTiles(b)...)
+}
+
+// gridCellOf returns the cell containing p.
+func gridCellOf(origin, p *geo.Point, cellSizeKm float64) (int, int) {
+	x, y := localXY(origin, p)
+	col := int(math.Floor(x/cellSizeKm + 0.5))
+	row := int(math.Floor(-y/cellSizeKm + 0.5))
+	return row, col
+}
+
+// gridCellCenter is the inverse of gridCellOf.
+func gridCellCenter(origin *geo.Point, cellSizeKm float64, row, col int) *geo.Point {
+	x := float64(col) * cellSizeKm
+	y := -float64(row) * cellSizeKm
+	lat := origin.Lat() + y/KM_PER_DEG_LAT
+	lng := origin.Lng() + x/(KM_PER_DEG_EQUAT*math.Cos(origin.Lat()*math.Pi/180.0))
+	return geo.NewPoint(lat, lng)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"math/rand"
+	"testing"
+)
+
+func TestGridRLERoundTrip(t *testing.T) {
+	noise := make([]byte, 200)
+	rng := rand.New(rand.NewSource(1))
+	for i := range noise {
+		noise[i] = byte(rng.Intn(GRID_MAX_VALUE + 1))
+	}
+	long := make([]byte, 100) // Runs longer than 16 cells are split.
+	long[99] = 7
+	tests := []struct {
+		name  string
+		cells []byte
+		size  int // Encoded bytes, -1 = don't care.
+	}{
+		{"empty", nil, 0},
+		{"one", []byte{5}, 1},
+		{"runs", []byte{0, 0, 0, 3, 3, 15, 0}, 4},
+		{"long run", long, 8},
+		{"noise", noise, -1},
+	}
+	for _, tt := range tests {
+		enc := encodeGridRLE(tt.cells)
+		if tt.size >= 0 && len(enc) != tt.size {
+			t.Errorf("%s: %d bytes encoded, want %d", tt.name, len(enc), tt.size)
+		}
+		dec, err := decodeGridRLE(enc, len(tt.cells))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err.Error())
+			continue
+		}
+		for i := range tt.cells {
+			if dec[i] != tt.cells[i] {
+				t.Errorf("%s: cell %d decoded as %d, want %d", tt.name, i, dec[i], tt.cells[i])
+				break
+			}
+		}
+	}
+	if _, err := decodeGridRLE([]byte{0x35}, 5); err == nil {
+		t.Errorf("wrong cell count decoded")
+	}
+	if dec, _ := decodeGridRLE(encodeGridRLE([]byte{200}), 1); dec[0] != GRID_MAX_VALUE {
+		t.Errorf("out of range value decoded as %d", dec[0])
+	}
+}
+
+func TestSplitGridTiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	whole := gridTile{ColOffset: -10, RowOffset: -8, Cols: 20, Rows: 16, Cells: make([]byte, 20*16)}
+	for i := range whole.Cells {
+		whole.Cells[i] = byte(rng.Intn(GRID_MAX_VALUE + 1))
+	}
+	tiles := splitGridTiles(whole)
+	if len(tiles) < 2 {
+		t.Fatalf("noise grid not split")
+	}
+	// Put the tiles back together.
+	got := make([]byte, len(whole.Cells))
+	seen := 0
+	for _, tile := range tiles {
+		if n := len(encodeGridRLE(tile.Cells)); n > MAX_GRID_BYTES {
+			t.Errorf("tile at %d,%d is %d bytes encoded", tile.ColOffset, tile.RowOffset, n)
+		}
+		for r := 0; r < tile.Rows; r++ {
+			for c := 0; c < tile.Cols; c++ {
+				row, col := tile.RowOffset-whole.RowOffset+r, tile.ColOffset-whole.ColOffset+c
+				got[row*whole.Cols+col] = tile.Cells[r*tile.Cols+c]
+				seen++
+			}
+		}
+	}
+	if seen != len(whole.Cells) {
+		t.Fatalf("tiles cover %d cells, want %d", seen, len(whole.Cells))
+	}
+	for i := range got {
+		if got[i] != whole.Cells[i] {
+			t.Fatalf("cell %d is %d after splitting, want %d", i, got[i], whole.Cells[i])
+		}
+	}
+}
+
+func TestGridCell(t *testing.T) {
+	origin := geo.NewPoint(44.25, -81.60)
+	for _, rc := range [][2]int{{0, 0}, {3, -4}, {-10, 12}, {25, 25}} {
+		row, col := gridCellOf(origin, gridCellCenter(origin, 4, rc[0], rc[1]), 4)
+		if row != rc[0] || col != rc[1] {
+			t.Errorf("cell %v round trips to %d,%d", rc, row, col)
+		}
+	}
+	// Rows increase to the south, columns to the east.
+	if row, col := gridCellOf(origin, geo.NewPoint(44.10, -81.40), 4); row <= 0 || col <= 0 {
+		t.Errorf("south east point in cell %d,%d", row, col)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	DEFAULT_DWELL = 10000 // Milliseconds. Dwell of the single slot when no hop schedule is configured.
+)
+
+// hopSlot is the position in the hop schedule at some time.
+type hopSlot struct {
+	Index     int
+	Entry     FreqSchemeEntry
+	Remaining time.Duration // Until the next hop.
+}
+
+// currentHop finds the slot in effect at t. Every station with the same schedule and a GPS clock
+// agrees on it, which is what lets receivers follow.
+func currentHop(scheme []FreqSchemeEntry, t time.Time) hopSlot {
+	cycle := int64(0)
+	for _, e := range scheme {
+		cycle += int64(e.Dwell)
+	}
+	if cycle <= 0 {
+		return hopSlot{Index: 0, Entry: scheme[0], Remaining: DEFAULT_DWELL * time.Millisecond}
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	pos := ms % cycle
+	for i, e := range scheme {
+		if pos < int64(e.Dwell) {
+			return hopSlot{Index: i, Entry: e, Remaining: time.Duration(int64(e.Dwell)-pos) * time.Millisecond}
+		}
+		pos -= int64(e.Dwell)
+	}
+	return hopSlot{Index: 0, Entry: scheme[0], Remaining: time.Duration(scheme[0].Dwell) * time.Millisecond} // Not reached.
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCurrentHop(t *testing.T) {
+	scheme := []FreqSchemeEntry{{Freq: 915.0, Dwell: 100}, {Freq: 916.0, Dwell: 300}, {Freq: 917.0, Dwell: 600}}
+	tests := []struct {
+		ms        int64 // GPS time, milliseconds since the epoch.
+		index     int
+		remaining time.Duration
+	}{
+		{0, 0, 100 * time.Millisecond},
+		{99, 0, 1 * time.Millisecond},
+		{100, 1, 300 * time.Millisecond},
+		{399, 1, 1 * time.Millisecond},
+		{400, 2, 600 * time.Millisecond},
+		{999, 2, 1 * time.Millisecond},
+		{1000, 0, 100 * time.Millisecond},
+		{1571500012345, 1, 55 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		slot := currentHop(scheme, time.Unix(0, tt.ms*int64(time.Millisecond)))
+		if slot.Index != tt.index || slot.Remaining != tt.remaining || slot.Entry != scheme[tt.index] {
+			t.Errorf("at %d ms: slot %d with %s left, want %d with %s", tt.ms, slot.Index, slot.Remaining, tt.index, tt.remaining)
+		}
+	}
+}
+
+func TestCurrentHopWithoutDwell(t *testing.T) {
+	scheme := []FreqSchemeEntry{{Freq: 915.0}}
+	slot := currentHop(scheme, time.Unix(1000, 0))
+	if slot.Index != 0 || slot.Remaining != DEFAULT_DWELL*time.Millisecond {
+		t.Errorf("slot %d with %s left", slot.Index, slot.Remaining)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	uatradio "../gouatradio"
+)
+
+const (
+	LBT_MODE_OFF  = ""
+	LBT_MODE_RSSI = "rssi" // Ask the TX radio for the channel RSSI.
+	LBT_MODE_RX   = "rx"   // Listen with a paired RX dongle. It only decodes our own modulation, see startLBT.
+
+	LBT_HOLD_TIME   = 50 * time.Millisecond  // A packet heard by the paired dongle keeps the channel busy this long.
+	LBT_LISTEN_TIME = 100 * time.Millisecond // The paired dongle listens this long after a retune before the channel counts as clear.
+)
+
+// lbtStatus counts channel samples and deferrals, for stats.
+type lbtStatus struct {
+	Samples   uint64
+	Busy      uint64
+	Deferrals uint64
+	GaveUp    uint64 // Messages dropped after LBTMaxDeferrals.
+}
+
+var lbtStats lbtStatus // Protected by lbtMutex.
+
+var lbtRadio *uatradio.UATRadio // Paired RX dongle in LBT_MODE_RX.
+var lbtMutex *sync.Mutex        // Protects the following and lbtStats.
+var lbtRadioFreq float64
+var lbtTunedAt time.Time   // When the paired dongle was tuned to lbtRadioFreq.
+var lbtLastHeard time.Time // Last packet above LBTThreshold heard by the paired dongle.
+
+var errChannelBusy = errors.New("txWeatherMessage(): channel busy.")
+
+// startLBT opens the paired RX dongle when listening through one. The dongle only hears packets it can
+// decode, i.e. other stations in our modulation. Other traffic in the band goes unnoticed, use
+// LBT_MODE_RSSI to back off from that too.
+func startLBT() error {
+	lbtMutex = &sync.Mutex{}
+	if globalSettings.LBTMode != LBT_MODE_RX {
+		return nil
+	}
+	log.Printf("LBT: the paired dongle only hears UAT weather stations, use LBTMode \"rssi\" to detect other traffic.\n")
+	r, err := uatradio.NewUATRadio(globalSettings.Freq, globalSettings.RadioModMode, globalSettings.LBTProductID)
+	if err != nil {
+		return err
+	}
+	lbtRadio = r
+	lbtRadioFreq = globalSettings.Freq
+	lbtTunedAt = sysClock.Now()
+	c := make(chan uatradio.UATRadioMessage, 1024)
+	lbtRadio.SubscribeListener(c)
+	go func() {
+		for m := range c {
+			if m.RSSI >= globalSettings.LBTThreshold {
+				lbtMutex.Lock()
+				lbtLastHeard = sysClock.Now()
+				lbtMutex.Unlock()
+			}
+		}
+	}()
+	return nil
+}
+
+// channelBusy samples freq. The TX radio must already be tuned to it.
+func channelBusy(u *uatradio.UATRadio, freq float64) bool {
+	busy := false
+	switch globalSettings.LBTMode {
+	case LBT_MODE_RSSI:
+		rssi, err := u.RSSI()
+		if err != nil {
+			log.Printf("LBT: can't read RSSI: %s\n", err.Error())
+			return false
+		}
+		busy = rssi >= globalSettings.LBTThreshold
+	case LBT_MODE_RX:
+		// One paired dongle serves all TX radios, it follows whichever is about to transmit. Having just
+		//  arrived on a channel it has heard nothing yet, so it listens for LBT_LISTEN_TIME first.
+		for {
+			lbtMutex.Lock()
+			if freq != lbtRadioFreq {
+				err := lbtRadio.SetFrequency(freq)
+				if err != nil {
+					lbtMutex.Unlock()
+					log.Printf("LBT: can't retune RX dongle to %0.3f MHz: %s\n", freq, err.Error())
+					return false
+				}
+				lbtRadioFreq = freq
+				lbtTunedAt = sysClock.Now()
+				lbtLastHeard = time.Time{} // What was heard was on the old channel.
+			}
+			now := sysClock.Now()
+			listened := now.Sub(lbtTunedAt)
+			if listened >= LBT_LISTEN_TIME {
+				busy = now.Sub(lbtLastHeard) < LBT_HOLD_TIME
+				lbtMutex.Unlock()
+				break
+			}
+			lbtMutex.Unlock()
+			sysClock.Sleep(LBT_LISTEN_TIME - listened) // Another radio may retune it meanwhile, then start over.
+		}
+	default:
+		return false
+	}
+	lbtMutex.Lock()
+	lbtStats.Samples++
+	if busy {
+		lbtStats.Busy++
+	}
+	lbtMutex.Unlock()
+	return busy
+}
+
+// lbtStatsCopy returns the stats as of now.
+func lbtStatsCopy() lbtStatus {
+	lbtMutex.Lock()
+	defer lbtMutex.Unlock()
+	return lbtStats
+}
+
+func lbtDeferred() {
+	lbtMutex.Lock()
+	lbtStats.Deferrals++
+	lbtMutex.Unlock()
+}
+
+func lbtGaveUp() {
+	lbtMutex.Lock()
+	lbtStats.GaveUp++
+	lbtMutex.Unlock()
+}
+
+// lbtBackoff is a random wait between LBTMinBackoff and LBTMaxBackoff milliseconds.
+func lbtBackoff() time.Duration {
+	min, max := globalSettings.LBTMinBackoff, globalSettings.LBTMaxBackoff
+	if max <= min {
+		return time.Duration(min) * time.Millisecond
+	}
+	return time.Duration(min+rand.Intn(max-min)) * time.Millisecond
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	uatradio "../gouatradio"
+)
+
+// startTestLBT sets up LBT_MODE_RX without a dongle.
+func startTestLBT(freq float64) {
+	resetSettings()
+	globalSettings.LBTMode = LBT_MODE_RX
+	lbtMutex = &sync.Mutex{}
+	lbtRadio = &uatradio.UATRadio{}
+	lbtRadioFreq = freq
+	lbtTunedAt = sysClock.Now()
+	lbtLastHeard = time.Time{}
+	lbtStats = lbtStatus{}
+}
+
+// sample runs channelBusy on the fake clock, hearing a packet at heardAfter if it is >= 0.
+func sample(c *fakeClock, freq float64, heardAfter time.Duration) (busy bool, took time.Duration) {
+	start := c.Now()
+	done := make(chan bool)
+	go func() { done <- channelBusy(nil, freq) }()
+	for {
+		select {
+		case busy := <-done:
+			return busy, c.Now().Sub(start)
+		case <-time.After(5 * time.Millisecond):
+		}
+		if heardAfter >= 0 && c.Now().Sub(start) >= heardAfter {
+			lbtMutex.Lock()
+			lbtLastHeard = c.Now()
+			lbtMutex.Unlock()
+			heardAfter = -1
+		}
+		c.advance(10 * time.Millisecond)
+	}
+}
+
+func TestLBTListensAfterRetune(t *testing.T) {
+	c := newFakeClock(time.Unix(6000, 0))
+	defer useClock(c)()
+	startTestLBT(868.1)
+
+	busy, took := sample(c, 868.3, -1)
+	if busy || took < LBT_LISTEN_TIME {
+		t.Errorf("quiet channel: busy %t after %s, want clear after at least %s", busy, took, LBT_LISTEN_TIME)
+	}
+	startTestLBT(868.1)
+	busy, _ = sample(c, 868.3, 80*time.Millisecond)
+	if !busy {
+		t.Errorf("packet heard while listening after a retune, but the channel is clear")
+	}
+}
+
+func TestLBTNoRetune(t *testing.T) {
+	c := newFakeClock(time.Unix(6000, 0))
+	defer useClock(c)()
+	startTestLBT(868.1)
+	c.advance(time.Second)
+
+	busy, took := sample(c, 868.1, -1)
+	if busy || took != 0 {
+		t.Errorf("settled quiet channel: busy %t after %s, want clear right away", busy, took)
+	}
+	lbtLastHeard = c.Now()
+	if busy, _ := sample(c, 868.1, -1); !busy {
+		t.Errorf("packet just heard, but the channel is clear")
+	}
+	if s := lbtStatsCopy(); s.Samples != 2 || s.Busy != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"github.com/kellydunn/golang-geo"
+	"log"
+	"time"
+)
+
+const (
+	LIGHTNING_WINDOW    = 15 * time.Minute // Strikes older than this are dropped from the grid.
+	LIGHTNING_CELLS     = 32               // Grid is LIGHTNING_CELLS x LIGHTNING_CELLS, centered on the station.
+	LIGHTNING_CELL_SIZE = 15.0             // km.
+)
+
+// lightningStrike is one message of the strike feed, e.g. {"time": 1571500000, "lat": 44.25, "lng": -81.60}.
+type lightningStrike struct {
+	Time int64   `json:"time"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+}
+
+var recentStrikes []lightningStrike // Protected by lookupMutex.
+
+func addLightningStrike(data []byte) {
+	var s lightningStrike
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		log.Printf("lightning: %s\n", err.Error())
+		return
+	}
+	if s.Time == 0 {
+		s.Time = sysClock.Now().Unix()
+	}
+	lookupMutex.Lock()
+	recentStrikes = append(recentStrikes, s)
+	lookupMutex.Unlock()
+}
+
+// pruneLightningStrikes drops strikes that have aged out of LIGHTNING_WINDOW. Caller holds lookupMutex.
+func pruneLightningStrikes(now time.Time) {
+	cutoff := now.Add(-LIGHTNING_WINDOW).Unix()
+	kept := recentStrikes[:0]
+	for _, s := range recentStrikes {
+		if s.Time >= cutoff {
+			kept = append(kept, s)
+		}
+	}
+	recentStrikes = kept
+}
+
+// lightningGrid counts recent strikes per cell around center. Counts saturate at GRID_MAX_VALUE.
+func lightningGrid(center *geo.Point, strikes []lightningStrike) gridTile {
+	t := gridTile{
+		ColOffset: -LIGHTNING_CELLS / 2,
+		RowOffset: -LIGHTNING_CELLS / 2,
+		Cols:      LIGHTNING_CELLS,
+		Rows:      LIGHTNING_CELLS,
+		Cells:     make([]byte, LIGHTNING_CELLS*LIGHTNING_CELLS),
+	}
+	for _, s := range strikes {
+		row, col := gridCellOf(center, geo.NewPoint(s.Lat, s.Lng), LIGHTNING_CELL_SIZE)
+		row -= t.RowOffset
+		col -= t.ColOffset
+		if row < 0 || row >= t.Rows || col < 0 || col >= t.Cols {
+			continue
+		}
+		if t.Cells[row*t.Cols+col] < GRID_MAX_VALUE {
+			t.Cells[row*t.Cols+col]++
+		}
+	}
+	return t
+}
+
+// createLightningWeatherMessages builds the strike grid around the station. Every tile is sent, empty
+// ones included, so that receivers clear strikes that have aged out.
+func createLightningWeatherMessages(strikes []lightningStrike) []*txwx.WeatherMessage {
+	if stationGeoPt == nil {
+		return nil
+	}
+	var msgs []*txwx.WeatherMessage
+	for _, t := range splitGridTiles(lightningGrid(stationGeoPt, strikes)) {
+		msgs = append(msgs, &txwx.WeatherMessage{
+			Type:            txwx.WeatherMessage_LIGHTNING,
+			TxTime:          uint32(sysClock.Now().Unix()),
+			StationLat:      Location.GPSLatitude,
+			StationLng:      Location.GPSLongitude,
+			ObservationTime: uint32(sysClock.Now().Unix()),
+			Grid: &txwx.Grid{
+				Cols:      uint32(t.Cols),
+				Rows:      uint32(t.Rows),
+				CellSize:  uint32(LIGHTNING_CELL_SIZE * 1000),
+				ColOffset: int32(t.ColOffset),
+				RowOffset: int32(t.RowOffset),
+				Cells:     encodeGridRLE(t.Cells),
+			},
+		})
+	}
+	return msgs
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"hash/crc64"
+	"sync"
+	"testing"
+	"time"
+)
+
+// strikeAt is a strike x km east and y km north of center.
+func strikeAt(center *geo.Point, x, y float64, t int64) lightningStrike {
+	p := fromLocalXY(center, x, y)
+	return lightningStrike{Time: t, Lat: p.Lat(), Lng: p.Lng()}
+}
+
+func TestLightningGrid(t *testing.T) {
+	center := geo.NewPoint(44.25, -81.60)
+	var strikes []lightningStrike
+	strikes = append(strikes, strikeAt(center, 0, 0, 1))
+	for i := 0; i < GRID_MAX_VALUE+5; i++ {
+		strikes = append(strikes, strikeAt(center, 30, 0, 1)) // Two cells east, saturates.
+	}
+	edge := LIGHTNING_CELLS / 2 * LIGHTNING_CELL_SIZE
+	strikes = append(strikes,
+		strikeAt(center, -edge, 0, 1),   // Westmost column.
+		strikeAt(center, edge, 0, 1),    // One column past the eastmost.
+		strikeAt(center, 0, -edge, 1),   // One row past the southmost.
+		strikeAt(center, 1000, 1000, 1), // Far outside.
+	)
+	g := lightningGrid(center, strikes)
+	if g.Cols != LIGHTNING_CELLS || g.Rows != LIGHTNING_CELLS || len(g.Cells) != LIGHTNING_CELLS*LIGHTNING_CELLS {
+		t.Fatalf("grid %dx%d, %d cells", g.Cols, g.Rows, len(g.Cells))
+	}
+	cell := func(row, col int) byte { return g.Cells[(row-g.RowOffset)*g.Cols+col-g.ColOffset] }
+	tests := []struct {
+		row, col int
+		want     byte
+	}{
+		{0, 0, 1},
+		{0, 2, GRID_MAX_VALUE},
+		{0, -LIGHTNING_CELLS / 2, 1},
+		{0, 1, 0},
+	}
+	total := 0
+	for _, v := range g.Cells {
+		total += int(v)
+	}
+	for _, tt := range tests {
+		if got := cell(tt.row, tt.col); got != tt.want {
+			t.Errorf("cell (%d, %d) = %d, want %d", tt.row, tt.col, got, tt.want)
+		}
+	}
+	if total != 2+GRID_MAX_VALUE {
+		t.Errorf("%d strikes on the grid, want %d", total, 2+GRID_MAX_VALUE)
+	}
+}
+
+func TestPruneLightningStrikes(t *testing.T) {
+	now := time.Unix(1571500000, 0)
+	recentStrikes = []lightningStrike{
+		{Time: now.Add(-LIGHTNING_WINDOW - time.Second).Unix(), Lat: 1},
+		{Time: now.Add(-LIGHTNING_WINDOW).Unix(), Lat: 2},
+		{Time: now.Add(-time.Minute).Unix(), Lat: 3},
+		{Time: now.Add(-LIGHTNING_WINDOW - time.Hour).Unix(), Lat: 4},
+		{Time: now.Unix(), Lat: 5},
+	}
+	defer func() { recentStrikes = nil }()
+	pruneLightningStrikes(now)
+	if len(recentStrikes) != 3 || recentStrikes[0].Lat != 2 || recentStrikes[1].Lat != 3 || recentStrikes[2].Lat != 5 {
+		t.Errorf("kept %+v", recentStrikes)
+	}
+}
+
+func TestCreateLightningWeatherMessages(t *testing.T) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	lookupMutex = &sync.Mutex{}
+	c := newFakeClock(time.Unix(1571500000, 0))
+	defer useClock(c)()
+	old := stationGeoPt
+	defer func() { stationGeoPt = old }()
+	stationGeoPt = nil
+	if msgs := createLightningWeatherMessages(nil); msgs != nil {
+		t.Errorf("%d messages without a position", len(msgs))
+	}
+	stationGeoPt = geo.NewPoint(44.25, -81.60)
+	// A strike in every other cell doesn't compress, so the grid has to be split.
+	var strikes []lightningStrike
+	half := float64(LIGHTNING_CELLS / 2)
+	for row := 0; row < LIGHTNING_CELLS; row++ {
+		for col := row % 2; col < LIGHTNING_CELLS; col += 2 {
+			strikes = append(strikes, strikeAt(stationGeoPt, (float64(col)-half)*LIGHTNING_CELL_SIZE, (half-float64(row))*LIGHTNING_CELL_SIZE, c.Now().Unix()))
+		}
+	}
+	msgs := createLightningWeatherMessages(strikes)
+	if len(msgs) < 2 {
+		t.Fatalf("%d messages, want the grid split", len(msgs))
+	}
+	cells, total := 0, 0
+	for _, m := range msgs {
+		if n := packetSize(m); n >= MAX_PACKET_LEN {
+			t.Errorf("tile %dx%d%+d%+d is %d bytes", m.Grid.Cols, m.Grid.Rows, m.Grid.ColOffset, m.Grid.RowOffset, n)
+		}
+		values, err := decodeGridRLE(m.Grid.Cells, int(m.Grid.Cols*m.Grid.Rows))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cells += len(values)
+		for _, v := range values {
+			total += int(v)
+		}
+	}
+	if cells != LIGHTNING_CELLS*LIGHTNING_CELLS || total != len(strikes) {
+		t.Errorf("tiles cover %d cells with %d strikes, want %d cells with %d", cells, total, LIGHTNING_CELLS*LIGHTNING_CELLS, len(strikes))
+	}
+}
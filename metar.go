@@ -0,0 +1,174 @@
+package main
+
+import (
+	"./proto"
+	"github.com/cyoung/ADDS"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	FEET_PER_METER = 3.28084
+	NO_CEILING     = 99999 // Ceiling value when there is no BKN/OVC/VV layer.
+)
+
+// metarFields holds the values we derive products from, parsed from the raw METAR text so we don't
+// depend on which fields the ADDS XML happens to fill in.
+type metarFields struct {
+	Ident        string
+	HasWind      bool
+	WindDir      int // Degrees true, -1 for variable.
+	WindSpeed    int // Knots.
+	WindGust     int // Knots.
+	HasVis       bool
+	Visibility   float64 // Statute miles.
+	Ceiling      int     // Feet AGL, NO_CEILING if none.
+	HasTemp      bool
+	TempC        int
+	HasAltimeter bool
+	Altimeter    float64 // inHg.
+}
+
+// parseVisibility handles "10SM", "1/2SM", "M1/4SM" and "P6SM".
+func parseVisibility(s string) (float64, bool) {
+	s = strings.TrimSuffix(s, "SM")
+	s = strings.TrimLeft(s, "MP")
+	if i := strings.Index(s, "/"); i > 0 {
+		num, err1 := strconv.Atoi(s[:i])
+		den, err2 := strconv.Atoi(s[i+1:])
+		if err1 != nil || err2 != nil || den == 0 {
+			return 0, false
+		}
+		return float64(num) / float64(den), true
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	return float64(v), true
+}
+
+func parseMETARTemp(s string) (int, bool) {
+	neg := strings.HasPrefix(s, "M")
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "M"))
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		v = -v
+	}
+	return v, true
+}
+
+func parseMETARText(text string) metarFields {
+	f := metarFields{Ceiling: NO_CEILING, WindDir: -1}
+	words := strings.Fields(text)
+	for i, w := range words {
+		if w == "RMK" {
+			break
+		}
+		switch {
+		case i == 0:
+			f.Ident = w
+		case strings.HasSuffix(w, "KT") && len(w) >= 7:
+			wind := strings.TrimSuffix(w, "KT")
+			if g := strings.Index(wind, "G"); g > 0 {
+				f.WindGust, _ = strconv.Atoi(wind[g+1:])
+				wind = wind[:g]
+			}
+			speed, err := strconv.Atoi(wind[3:])
+			if err != nil {
+				continue
+			}
+			f.WindSpeed = speed
+			f.HasWind = true
+			if dir, err := strconv.Atoi(wind[:3]); err == nil {
+				f.WindDir = dir
+			}
+		case strings.HasSuffix(w, "SM"):
+			v, ok := parseVisibility(w)
+			if !ok {
+				continue
+			}
+			// "1 1/2SM": whole miles are a separate word.
+			if i > 0 {
+				if whole, err := strconv.Atoi(words[i-1]); err == nil && whole < 10 {
+					v += float64(whole)
+				}
+			}
+			f.Visibility = v
+			f.HasVis = true
+		case len(w) >= 5 && (strings.HasPrefix(w, "BKN") || strings.HasPrefix(w, "OVC") || strings.HasPrefix(w, "VV")):
+			height := strings.TrimLeft(w, "BKNOVC")
+			if len(height) > 3 {
+				height = height[:3] // Drop CB/TCU.
+			}
+			h, err := strconv.Atoi(height)
+			if err == nil && h*100 < f.Ceiling {
+				f.Ceiling = h * 100
+			}
+		case strings.Contains(w, "/") && len(w) >= 3 && len(w) <= 7:
+			parts := strings.Split(w, "/")
+			if t, ok := parseMETARTemp(parts[0]); ok && !f.HasTemp {
+				f.TempC = t
+				f.HasTemp = true
+			}
+		case len(w) == 5 && (w[0] == 'A' || w[0] == 'Q'):
+			v, err := strconv.Atoi(w[1:])
+			if err != nil {
+				continue
+			}
+			if w[0] == 'A' {
+				f.Altimeter = float64(v) / 100.0
+			} else {
+				f.Altimeter = float64(v) / MB_PER_INHG // hPa.
+			}
+			f.HasAltimeter = true
+		}
+	}
+	return f
+}
+
+// flightCategory classifies by ceiling and visibility. Unknown without a visibility.
+func flightCategory(f metarFields) txwx.Derived_FlightCategory {
+	if !f.HasVis {
+		return txwx.Derived_UNKNOWN
+	}
+	switch {
+	case f.Ceiling < 500 || f.Visibility < 1:
+		return txwx.Derived_LIFR
+	case f.Ceiling < 1000 || f.Visibility < 3:
+		return txwx.Derived_IFR
+	case f.Ceiling <= 3000 || f.Visibility <= 5:
+		return txwx.Derived_MVFR
+	}
+	return txwx.Derived_VFR
+}
+
+// densityAltitude in feet, from temperature, altimeter setting and field elevation (NWS formula).
+func densityAltitude(tempC int, altimeter float64, elevationFt float64) int {
+	elevationM := elevationFt / FEET_PER_METER
+	stationPressure := altimeter * math.Pow((288.0-0.0065*elevationM)/288.0, 5.2561)
+	tempF := float64(tempC)*9.0/5.0 + 32.0
+	da := 145442.16 * (1 - math.Pow(17.326*stationPressure/(459.67+tempF), 0.235))
+	return int(math.Floor(da + 0.5))
+}
+
+func createDerivedWeatherMessage(metar ADDS.ADDSMETAR) *txwx.WeatherMessage {
+	f := parseMETARText(metar.Text)
+	derived := &txwx.Derived{FlightCategory: flightCategory(f)}
+	if f.HasTemp && f.HasAltimeter {
+		derived.DensityAltitude = int32(densityAltitude(f.TempC, f.Altimeter, metar.Elevation*FEET_PER_METER))
+		derived.HasDensityAltitude = true
+	}
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_DERIVED,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(metar.Observation.Time.Unix()),
+		Ident:           f.Ident,
+		Derived:         derived,
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"./proto"
+	"testing"
+)
+
+func TestFlightCategory(t *testing.T) {
+	tests := []struct {
+		ceiling int
+		vis     float64
+		hasVis  bool
+		want    txwx.Derived_FlightCategory
+	}{
+		{NO_CEILING, 10, false, txwx.Derived_UNKNOWN},
+		{NO_CEILING, 10, true, txwx.Derived_VFR},
+		{NO_CEILING, 6, true, txwx.Derived_VFR},
+		{NO_CEILING, 5, true, txwx.Derived_MVFR},
+		{NO_CEILING, 3, true, txwx.Derived_MVFR},
+		{NO_CEILING, 2.5, true, txwx.Derived_IFR},
+		{NO_CEILING, 1, true, txwx.Derived_IFR},
+		{NO_CEILING, 0.75, true, txwx.Derived_LIFR},
+		{3100, 10, true, txwx.Derived_VFR},
+		{3000, 10, true, txwx.Derived_MVFR},
+		{1000, 10, true, txwx.Derived_MVFR},
+		{900, 10, true, txwx.Derived_IFR},
+		{500, 10, true, txwx.Derived_IFR},
+		{400, 10, true, txwx.Derived_LIFR},
+		{2000, 0.5, true, txwx.Derived_LIFR}, // The worse of the two wins.
+	}
+	for _, tt := range tests {
+		f := metarFields{Ceiling: tt.ceiling, Visibility: tt.vis, HasVis: tt.hasVis}
+		if got := flightCategory(f); got != tt.want {
+			t.Errorf("ceiling %d, visibility %g: %s, want %s", tt.ceiling, tt.vis, got, tt.want)
+		}
+	}
+}
+
+func TestParseMETARText(t *testing.T) {
+	tests := []struct {
+		text string
+		want metarFields
+	}{
+		{"KXYZ 191200Z 09010G18KT 1 1/2SM BR BKN008 OVC015 10/09 A2992 RMK AO2 SLP132",
+			metarFields{Ident: "KXYZ", HasWind: true, WindDir: 90, WindSpeed: 10, WindGust: 18, HasVis: true, Visibility: 1.5,
+				Ceiling: 800, HasTemp: true, TempC: 10, HasAltimeter: true, Altimeter: 29.92}},
+		{"CYXU 191200Z VRB03KT 15SM FEW040 M02/M05 Q1013",
+			metarFields{Ident: "CYXU", HasWind: true, WindDir: -1, WindSpeed: 3, HasVis: true, Visibility: 15,
+				Ceiling: NO_CEILING, HasTemp: true, TempC: -2, HasAltimeter: true, Altimeter: 1013 / MB_PER_INHG}},
+		{"KABC 191200Z AUTO 00000KT M1/4SM FG VV002 08/08 A3001",
+			metarFields{Ident: "KABC", HasWind: true, WindDir: 0, HasVis: true, Visibility: 0.25,
+				Ceiling: 200, HasTemp: true, TempC: 8, HasAltimeter: true, Altimeter: 30.01}},
+	}
+	for _, tt := range tests {
+		if got := parseMETARText(tt.text); got != tt.want {
+			t.Errorf("%s:\n got  %+v\n want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDensityAltitude(t *testing.T) {
+	tests := []struct {
+		tempC     int
+		altimeter float64
+		elevation float64
+		want      int // NWS formula, worked independently.
+	}{
+		{15, 29.92, 0, 18},
+		{30, 29.92, 0, 1742},
+		{-10, 29.92, 0, -3117},
+		{30, 30.00, 5000, 7736},
+		{35, 29.80, 6500, 10285},
+	}
+	for _, tt := range tests {
+		got := densityAltitude(tt.tempC, tt.altimeter, tt.elevation)
+		if got < tt.want-1 || got > tt.want+1 {
+			t.Errorf("%d C, %0.2f inHg, %0.0f ft: %d ft, want %d", tt.tempC, tt.altimeter, tt.elevation, got, tt.want)
+		}
+	}
+}
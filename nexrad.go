@@ -0,0 +1,116 @@
+package main
+
+import (
+	"github.com/cyoung/uatsynth"
+	"github.com/kellydunn/golang-geo"
+	"math"
+	"time"
+)
+
+// FIS-B regional NEXRAD block layout (DO-267A). Blocks are numbered 450 * (lat / 4') + (east lon / 48')
+// below 60N, and hold 32 x 4 bins of 1.5' x 1', ordered west to east and north to south.
+const (
+	NEXRAD_BLOCK_WIDTH      = 48.0 // Arc minutes of longitude.
+	NEXRAD_BLOCK_HEIGHT     = 4.0  // Arc minutes of latitude.
+	NEXRAD_BLOCK_COLS       = 32
+	NEXRAD_BLOCK_ROWS       = 4
+	NEXRAD_BLOCKS_PER_ROW   = 450
+	NEXRAD_MAX_LAT          = 60.0 // Blocks above this are twice as wide. Not supported.
+	NEXRAD_PRODUCT_REGIONAL = 63
+)
+
+// radarMosaic collects the radar tiles of one station until they form the full grid.
+type radarMosaic struct {
+	Origin          *geo.Point
+	CellSize        float64 // km.
+	ObservationTime uint32
+	Cells           map[[2]int]byte // Keyed by (row, col).
+}
+
+var radarMosaics = make(map[string]*radarMosaic) // Keyed by station position.
+
+// update merges a tile. A tile from a newer image starts a new mosaic, stragglers from older images
+// are dropped.
+func (m *radarMosaic) update(t gridTile, observationTime uint32) bool {
+	if observationTime < m.ObservationTime {
+		return false
+	}
+	if observationTime > m.ObservationTime {
+		m.Cells = make(map[[2]int]byte)
+		m.ObservationTime = observationTime
+	}
+	for i, v := range t.Cells {
+		m.Cells[[2]int{t.RowOffset + i/t.Cols, t.ColOffset + i%t.Cols}] = v
+	}
+	return true
+}
+
+func (m *radarMosaic) value(p *geo.Point) byte {
+	row, col := gridCellOf(m.Origin, p, m.CellSize)
+	return m.Cells[[2]int{row, col}]
+}
+
+func eastLongitude(lng float64) float64 {
+	if lng < 0 {
+		return lng + 360
+	}
+	return lng
+}
+
+// encodeNexradBlock builds the FIS-B data of one run-length encoded block: three header bytes (RLE flag,
+// block number) followed by one byte per run, (length-1)<<3 | intensity.
+func encodeNexradBlock(blockNum int, bins []byte) []byte {
+	buf := []byte{0x80 | byte(blockNum>>16)&0x0f, byte(blockNum >> 8), byte(blockNum)}
+	for i := 0; i < len(bins); {
+		run := 1
+		for i+run < len(bins) && run < 32 && bins[i+run] == bins[i] {
+			run++
+		}
+		buf = append(buf, byte(run-1)<<3|bins[i]&0x07)
+		i += run
+	}
+	return buf
+}
+
+// nexradFrames renders the blocks covering tile t from the station's mosaic.
+func nexradFrames(m *radarMosaic, t gridTile) []*uatsynth.UATFrame {
+	// Bounds of the tile: centers of its corner cells, widened by half a cell.
+	nw := gridCellCenter(m.Origin, m.CellSize, t.RowOffset, t.ColOffset)
+	se := gridCellCenter(m.Origin, m.CellSize, t.RowOffset+t.Rows-1, t.ColOffset+t.Cols-1)
+	padLat := m.CellSize / 2 / KM_PER_DEG_LAT
+	padLng := m.CellSize / 2 / (KM_PER_DEG_EQUAT * math.Cos(m.Origin.Lat()*math.Pi/180.0))
+	minLat := se.Lat() - padLat
+	maxLat := math.Min(nw.Lat()+padLat, NEXRAD_MAX_LAT-0.001)
+	minLng := eastLongitude(nw.Lng() - padLng)
+	maxLng := eastLongitude(se.Lng() + padLng)
+	if minLat < 0 || minLat >= NEXRAD_MAX_LAT {
+		return nil // Northern hemisphere below 60N only.
+	}
+	observationTime := time.Unix(int64(m.ObservationTime), 0).UTC()
+	var frames []*uatsynth.UATFrame
+	for latIdx := int(minLat * 60 / NEXRAD_BLOCK_HEIGHT); latIdx <= int(maxLat*60/NEXRAD_BLOCK_HEIGHT); latIdx++ {
+		for lngIdx := int(minLng * 60 / NEXRAD_BLOCK_WIDTH); lngIdx <= int(maxLng*60/NEXRAD_BLOCK_WIDTH); lngIdx++ {
+			south := float64(latIdx) * NEXRAD_BLOCK_HEIGHT / 60
+			west := float64(lngIdx%NEXRAD_BLOCKS_PER_ROW) * NEXRAD_BLOCK_WIDTH / 60
+			bins := make([]byte, 0, NEXRAD_BLOCK_COLS*NEXRAD_BLOCK_ROWS)
+			for r := 0; r < NEXRAD_BLOCK_ROWS; r++ {
+				lat := south + (NEXRAD_BLOCK_HEIGHT-float64(r)-0.5)/60
+				for c := 0; c < NEXRAD_BLOCK_COLS; c++ {
+					lng := west + (float64(c)+0.5)*(NEXRAD_BLOCK_WIDTH/NEXRAD_BLOCK_COLS)/60
+					if lng > 180 {
+						lng -= 360
+					}
+					bins = append(bins, m.value(geo.NewPoint(lat, lng))>>1) // 4-bit to 3-bit intensity.
+				}
+			}
+			f := new(uatsynth.UATFrame)
+			f.Product_id = NEXRAD_PRODUCT_REGIONAL
+			f.Frame_type = 0
+			f.FISB_hours = uint32(observationTime.Hour())
+			f.FISB_minutes = uint32(observationTime.Minute())
+			f.FISB_data = encodeNexradBlock(latIdx*NEXRAD_BLOCKS_PER_ROW+lngIdx%NEXRAD_BLOCKS_PER_ROW, bins)
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
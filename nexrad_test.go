@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"github.com/kellydunn/golang-geo"
+	"math"
+	"testing"
+)
+
+func TestEncodeNexradBlock(t *testing.T) {
+	bins := make([]byte, NEXRAD_BLOCK_COLS*NEXRAD_BLOCK_ROWS)
+	for i := 40; i < 43; i++ {
+		bins[i] = 5
+	}
+	bins[43] = 9 // Only 3 bits go out.
+	want := []byte{
+		0x81, 0x23, 0x45, // RLE, block 0x12345.
+		31<<3 | 0, 7<<3 | 0, // 40 empty bins, runs are at most 32 long.
+		2<<3 | 5,
+		0<<3 | 1,
+		31<<3 | 0, 31<<3 | 0, 19<<3 | 0, // The remaining 84.
+	}
+	if got := encodeNexradBlock(0x12345, bins); !bytes.Equal(got, want) {
+		t.Errorf("encodeNexradBlock() = % x, want % x", got, want)
+	}
+}
+
+// nexradBins expands an encoded block back into its bins.
+func nexradBins(data []byte) []byte {
+	var bins []byte
+	for _, b := range data[3:] {
+		for i := 0; i <= int(b>>3); i++ {
+			bins = append(bins, b&0x07)
+		}
+	}
+	return bins
+}
+
+func TestNexradFrames(t *testing.T) {
+	origin := geo.NewPoint(44.25, -81.60)
+	m := &radarMosaic{Origin: origin, CellSize: 4, Cells: make(map[[2]int]byte)}
+	tile := gridTile{ColOffset: 0, RowOffset: 0, Cols: 1, Rows: 1, Cells: []byte{GRID_MAX_VALUE}}
+	if !m.update(tile, 1571500000) {
+		t.Fatalf("tile not taken")
+	}
+	if m.update(tile, 1571499000) {
+		t.Errorf("tile of an older image taken")
+	}
+	// The block holding the station, and the bin within it.
+	latMin, lngMin := origin.Lat()*60, eastLongitude(origin.Lng())*60
+	latIdx, lngIdx := int(latMin/NEXRAD_BLOCK_HEIGHT), int(lngMin/NEXRAD_BLOCK_WIDTH)
+	block := latIdx*NEXRAD_BLOCKS_PER_ROW + lngIdx
+	row := int(math.Floor(NEXRAD_BLOCK_HEIGHT - (latMin - float64(latIdx)*NEXRAD_BLOCK_HEIGHT)))
+	col := int(math.Floor((lngMin - float64(lngIdx)*NEXRAD_BLOCK_WIDTH) / (NEXRAD_BLOCK_WIDTH / NEXRAD_BLOCK_COLS)))
+
+	frames := nexradFrames(m, tile)
+	if len(frames) == 0 || len(frames) > 4 {
+		t.Fatalf("%d frames for one 4 km cell", len(frames))
+	}
+	found := false
+	for _, f := range frames {
+		if f.Product_id != NEXRAD_PRODUCT_REGIONAL {
+			t.Errorf("product %d", f.Product_id)
+		}
+		n := int(f.FISB_data[0]&0x0f)<<16 | int(f.FISB_data[1])<<8 | int(f.FISB_data[2])
+		bins := nexradBins(f.FISB_data)
+		if len(bins) != NEXRAD_BLOCK_COLS*NEXRAD_BLOCK_ROWS {
+			t.Fatalf("block %d has %d bins", n, len(bins))
+		}
+		if n != block {
+			continue
+		}
+		found = true
+		if v := bins[row*NEXRAD_BLOCK_COLS+col]; v != GRID_MAX_VALUE>>1 {
+			t.Errorf("bin (%d, %d) at the station = %d", row, col, v)
+		}
+		lit := 0
+		for _, v := range bins {
+			if v > 0 {
+				lit++
+			}
+		}
+		if lit > 9 {
+			t.Errorf("%d bins lit by one 4 km cell", lit)
+		}
+	}
+	if !found {
+		t.Errorf("no frame for block %d", block)
+	}
+
+	m.Origin = geo.NewPoint(61, -150)
+	if frames := nexradFrames(m, tile); frames != nil {
+		t.Errorf("%d frames above 60N", len(frames))
+	}
+}
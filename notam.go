@@ -0,0 +1,96 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NOTAM is one entry of the operator's NOTAM file, e.g.
+//
+//	[{"Ident": "KXYZ 10/001", "Text": "RWY 09/27 CLSD", "Effective": "2019-10-19T14:00:00Z", "Expires": "2019-10-19T16:00:00Z"}]
+//
+// A missing Effective means the NOTAM is in effect immediately, a missing Expires means it is permanent
+// until removed from the file.
+type NOTAM struct {
+	Ident     string
+	Text      string
+	Effective time.Time
+	Expires   time.Time
+}
+
+func (n NOTAM) Active(t time.Time) bool {
+	if t.Before(n.Effective) {
+		return false
+	}
+	return n.Expires.IsZero() || t.Before(n.Expires)
+}
+
+func readNOTAMFile(fn string) ([]NOTAM, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var notams []NOTAM
+	err = json.Unmarshal(buf, &notams)
+	if err != nil {
+		return nil, err
+	}
+	return notams, nil
+}
+
+// loadNOTAMs reads the operator NOTAM file, or every *.json file when path is a directory. Bad files
+// and overly long entries are logged and skipped so that one typo doesn't take down the rest.
+func loadNOTAMs(path string) ([]NOTAM, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if fi.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var ret []NOTAM
+	for _, fn := range files {
+		notams, err := readNOTAMFile(fn)
+		if err != nil {
+			log.Printf("can't read NOTAMs %s: %s\n", fn, err.Error())
+			continue
+		}
+		for _, n := range notams {
+			if size := packetSize(createNOTAMWeatherMessage(n)); size >= MAX_PACKET_LEN {
+				log.Printf("NOTAM %s in %s is too long (%d bytes, %d max), skipping.\n", n.Ident, fn, size, MAX_PACKET_LEN-1)
+				continue
+			}
+			ret = append(ret, n)
+		}
+	}
+	return ret, nil
+}
+
+func createNOTAMWeatherMessage(n NOTAM) *txwx.WeatherMessage {
+	msg := &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_NOTAM,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(sysClock.Now().Unix()),
+		Ident:           n.Ident,
+		TextData:        n.Text,
+	}
+	if !n.Effective.IsZero() {
+		msg.ObservationTime = uint32(n.Effective.Unix())
+		msg.ValidFrom = uint32(n.Effective.Unix())
+	}
+	if !n.Expires.IsZero() {
+		msg.ValidTo = uint32(n.Expires.Unix())
+	}
+	return msg
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadNOTAMs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txwx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.json":   `[{"Ident": "KXYZ 10/001", "Text": "RWY 09/27 CLSD", "Expires": "2019-10-19T16:00:00Z"}]`,
+		"b.json":   `[{"Ident": "KXYZ 10/002", "Text": "` + strings.Repeat("X", 140) + `"}, {"Ident": "KXYZ 10/003", "Text": "TWY A CLSD"}]`,
+		"bad.json": `[{"Ident": `,
+		"skip.txt": `[{"Ident": "KXYZ 10/004", "Text": "NOT JSON FILE"}]`,
+	}
+	for fn, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, fn), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notams, err := loadNOTAMs(dir)
+	if err != nil {
+		t.Fatalf("loadNOTAMs: %s", err.Error())
+	}
+	if len(notams) != 2 || notams[0].Ident != "KXYZ 10/001" || notams[1].Ident != "KXYZ 10/003" {
+		t.Fatalf("loaded %+v", notams)
+	}
+	expires := time.Date(2019, 10, 19, 16, 0, 0, 0, time.UTC)
+	if !notams[0].Active(expires.Add(-time.Second)) || notams[0].Active(expires) {
+		t.Errorf("Active() wrong around Expires")
+	}
+}
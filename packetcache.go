@@ -0,0 +1,106 @@
+package main
+
+import (
+	"./proto"
+	"encoding/binary"
+	"fmt"
+	"github.com/cyoung/ADDS"
+	"github.com/golang/protobuf/proto"
+	"hash/crc64"
+	"hash/fnv"
+	"sync/atomic"
+)
+
+const (
+	TX_TIME_TAG = 2<<3 | 0 // WeatherMessage.tx_time, varint.
+)
+
+// cachedPacket is a report encoded once per version. The body is marshaled without tx_time, which is
+// appended on every send. Protobuf takes fields in any order, and CRC-64 can be extended from the
+// body's checksum, so a send is a few byte copies instead of a marshal and a full checksum.
+type cachedPacket struct {
+	Version string
+	Msg     *txwx.WeatherMessage
+	Body    []byte
+	CRC     uint64
+}
+
+type packetCacheStats struct {
+	Hits   uint64 // Atomic.
+	Misses uint64 // Atomic.
+}
+
+// packetCache holds the encoded carousel reports. Only the main loop touches the entries.
+type packetCache struct {
+	entries map[string]*cachedPacket
+	used    map[string]bool // Keys looked up since the last sweep.
+}
+
+var cacheStats packetCacheStats
+
+func newPacketCache() *packetCache {
+	return &packetCache{entries: make(map[string]*cachedPacket), used: make(map[string]bool)}
+}
+
+// sweep drops the reports nobody has looked up since the last sweep, i.e. those gone from the data.
+// Changed reports don't need it, lookup replaces them.
+func (c *packetCache) sweep() {
+	for k := range c.entries {
+		if !c.used[k] {
+			delete(c.entries, k)
+		}
+	}
+	c.used = make(map[string]bool)
+}
+
+// lookup returns the encoded report for key at version, calling build only when it isn't cached. The
+// body carries the station position, so a report encoded before the station moved is built again.
+func (c *packetCache) lookup(key, version string, build func() *txwx.WeatherMessage) *cachedPacket {
+	c.used[key] = true
+	if p, ok := c.entries[key]; ok && p.Version == version && p.Msg.StationLat == Location.GPSLatitude && p.Msg.StationLng == Location.GPSLongitude {
+		atomic.AddUint64(&cacheStats.Hits, 1)
+		return p
+	}
+	atomic.AddUint64(&cacheStats.Misses, 1)
+	msg := build()
+	msg.TxTime = 0
+	msg.StationId = stationIDHash()
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	p := &cachedPacket{
+		Version: version,
+		Msg:     msg,
+		Body:    body,
+		CRC:     crc64.Checksum(body, crc64Table),
+	}
+	c.entries[key] = p
+	return p
+}
+
+// reportsFingerprint changes whenever any of the reports the cache holds does.
+func reportsFingerprint(metars []ADDS.ADDSMETAR, tafs []ADDS.ADDSTAF) uint64 {
+	h := fnv.New64a()
+	for _, v := range metars {
+		fmt.Fprintf(h, "%s\n%d\n%s\n", v.StationID, v.Observation.Time.Unix(), v.Text)
+	}
+	for _, v := range tafs {
+		fmt.Fprintf(h, "%s\n%d\n%s\n", v.StationID, v.BulletinTime.Time.Unix(), v.Text)
+	}
+	return h.Sum64()
+}
+
+// packet frames the report for a send at txTime.
+func (p *cachedPacket) packet(txTime uint32) []byte {
+	var tail [1 + binary.MaxVarintLen32]byte
+	tail[0] = TX_TIME_TAG
+	n := 1 + binary.PutUvarint(tail[1:], uint64(txTime))
+	crc := crc64.Update(p.CRC, crc64Table, tail[:n])
+
+	data := make([]byte, PACKET_HEADER_LEN, PACKET_HEADER_LEN+len(p.Body)+n)
+	binary.LittleEndian.PutUint16(data, uint16(len(p.Body)+n))
+	binary.LittleEndian.PutUint64(data[2:], crc)
+	data = append(data, p.Body...)
+	return append(data, tail[:n]...)
+}
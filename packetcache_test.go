@@ -0,0 +1,141 @@
+package main
+
+import (
+	"./proto"
+	"encoding/binary"
+	"github.com/cyoung/ADDS"
+	"github.com/golang/protobuf/proto"
+	"hash/crc64"
+	"testing"
+	"time"
+)
+
+func testMETARMessage() *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_METAR,
+		TxTime:          uint32(time.Now().Unix()),
+		StationLat:      39.8561,
+		StationLng:      -104.6737,
+		ObservationTime: 1571514780,
+		Ident:           "KDEN",
+		TextData:        "KDEN 191953Z 27010KT 10SM FEW080 SCT200 24/06 A3002 RMK AO2 SLP122",
+	}
+}
+
+// decodePacket checks the header the way RX does and returns the message.
+func decodePacket(t *testing.T, data []byte) *txwx.WeatherMessage {
+	if len(data) < PACKET_HEADER_LEN {
+		t.Fatalf("packet of %d bytes", len(data))
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	crc := binary.LittleEndian.Uint64(data[2:])
+	body := data[PACKET_HEADER_LEN:]
+	if n != len(body) {
+		t.Fatalf("length %d, body is %d bytes", n, len(body))
+	}
+	if crc64.Checksum(body, crc64Table) != crc {
+		t.Fatalf("CRC mismatch")
+	}
+	msg := &txwx.WeatherMessage{}
+	if err := proto.Unmarshal(body, msg); err != nil {
+		t.Fatalf("unmarshal: %s", err.Error())
+	}
+	return msg
+}
+
+func TestCachedPacketDecodes(t *testing.T) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	c := newPacketCache()
+	p := c.lookup("METAR KDEN", "1", testMETARMessage)
+	// Varints of every length, and 0, which a marshal leaves out.
+	for _, txTime := range []uint32{0, 1, 127, 128, 16383, 16384, 1571514800, 0xffffffff} {
+		want := testMETARMessage()
+		want.TxTime = txTime
+		fresh := decodePacket(t, preparePacketFromWeatherMessage(want))
+		cached := decodePacket(t, p.packet(txTime))
+		if !proto.Equal(fresh, cached) {
+			t.Errorf("tx_time %d: cached packet decodes to %v, fresh one to %v", txTime, cached, fresh)
+		}
+		if cached.TxTime != txTime {
+			t.Errorf("tx_time %d: decoded %d", txTime, cached.TxTime)
+		}
+	}
+}
+
+func TestPacketCacheLookup(t *testing.T) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	old := Location
+	defer func() { Location = old }()
+	Location.GPSLatitude, Location.GPSLongitude = 39.8561, -104.6737 // Where testMETARMessage() was built.
+	c := newPacketCache()
+	built := 0
+	build := func() *txwx.WeatherMessage {
+		built++
+		return testMETARMessage()
+	}
+	c.lookup("METAR KDEN", "1", build)
+	c.lookup("METAR KDEN", "1", build)
+	if built != 1 {
+		t.Errorf("built %d times for one version", built)
+	}
+	c.lookup("METAR KDEN", "2", build)
+	if built != 2 {
+		t.Errorf("new version not rebuilt")
+	}
+	c.sweep()
+	c.lookup("METAR KDEN", "2", build)
+	if built != 2 {
+		t.Errorf("rebuilt after a sweep")
+	}
+	c.sweep()
+	c.sweep() // Not looked up since the last one.
+	c.lookup("METAR KDEN", "2", build)
+	if built != 3 {
+		t.Errorf("report gone from the data not swept")
+	}
+	Location.GPSLatitude += 0.5
+	if p := c.lookup("METAR KDEN", "2", func() *txwx.WeatherMessage {
+		built++
+		msg := testMETARMessage()
+		msg.StationLat = Location.GPSLatitude
+		return msg
+	}); built != 4 || p.Msg.StationLat != Location.GPSLatitude {
+		t.Errorf("not rebuilt after the station moved")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			preparePacketFromWeatherMessage(testMETARMessage())
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		old := Location
+		defer func() { Location = old }()
+		Location.GPSLatitude, Location.GPSLongitude = 39.8561, -104.6737
+		c := newPacketCache()
+		for i := 0; i < b.N; i++ {
+			c.lookup("METAR KDEN", "1", testMETARMessage).packet(uint32(i))
+		}
+	})
+}
+
+func TestReportsFingerprint(t *testing.T) {
+	var m ADDS.ADDSMETAR
+	m.StationID = "KDEN"
+	m.Text = "KDEN 191953Z 27010KT"
+	m.Observation.Time = time.Unix(1571514780, 0)
+	a := reportsFingerprint([]ADDS.ADDSMETAR{m}, nil)
+	if a != reportsFingerprint([]ADDS.ADDSMETAR{m}, nil) {
+		t.Errorf("same reports, different fingerprint")
+	}
+	m.Text = "KDEN 191953Z 27011KT"
+	if a == reportsFingerprint([]ADDS.ADDSMETAR{m}, nil) {
+		t.Errorf("changed METAR, same fingerprint")
+	}
+	if a == reportsFingerprint(nil, nil) {
+		t.Errorf("dropped METAR, same fingerprint")
+	}
+}
@@ -9,6 +9,9 @@ It is generated from these files:
 
 It has these top-level messages:
 	ServerStatus
+	Area
+	Grid
+	Derived
 	WeatherMessage
 */
 package txwx
@@ -28,29 +31,137 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type Hazard int32
+
+const (
+	Hazard_HAZARD_NONE Hazard = 0
+	Hazard_CONVECTIVE  Hazard = 1
+	Hazard_TURBULENCE  Hazard = 2
+	Hazard_ICING       Hazard = 3
+	Hazard_IFR         Hazard = 4
+	Hazard_MTN_OBSCN   Hazard = 5
+	Hazard_ASH         Hazard = 6
+	Hazard_LLWS        Hazard = 7
+	Hazard_SFC_WIND    Hazard = 8
+)
+
+var Hazard_name = map[int32]string{
+	0: "HAZARD_NONE",
+	1: "CONVECTIVE",
+	2: "TURBULENCE",
+	3: "ICING",
+	4: "IFR",
+	5: "MTN_OBSCN",
+	6: "ASH",
+	7: "LLWS",
+	8: "SFC_WIND",
+}
+var Hazard_value = map[string]int32{
+	"HAZARD_NONE": 0,
+	"CONVECTIVE":  1,
+	"TURBULENCE":  2,
+	"ICING":       3,
+	"IFR":         4,
+	"MTN_OBSCN":   5,
+	"ASH":         6,
+	"LLWS":        7,
+	"SFC_WIND":    8,
+}
+
+func (x Hazard) String() string {
+	return proto.EnumName(Hazard_name, int32(x))
+}
+func (Hazard) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type Derived_FlightCategory int32
+
+const (
+	Derived_UNKNOWN Derived_FlightCategory = 0
+	Derived_VFR     Derived_FlightCategory = 1
+	Derived_MVFR    Derived_FlightCategory = 2
+	Derived_IFR     Derived_FlightCategory = 3
+	Derived_LIFR    Derived_FlightCategory = 4
+)
+
+var Derived_FlightCategory_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "VFR",
+	2: "MVFR",
+	3: "IFR",
+	4: "LIFR",
+}
+var Derived_FlightCategory_value = map[string]int32{
+	"UNKNOWN": 0,
+	"VFR":     1,
+	"MVFR":    2,
+	"IFR":     3,
+	"LIFR":    4,
+}
+
+func (x Derived_FlightCategory) String() string {
+	return proto.EnumName(Derived_FlightCategory_name, int32(x))
+}
+func (Derived_FlightCategory) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
+
 type WeatherMessage_Type int32
 
 const (
-	WeatherMessage_METAR  WeatherMessage_Type = 0
-	WeatherMessage_TAF    WeatherMessage_Type = 1
-	WeatherMessage_BEACON WeatherMessage_Type = 2
+	WeatherMessage_METAR       WeatherMessage_Type = 0
+	WeatherMessage_TAF         WeatherMessage_Type = 1
+	WeatherMessage_BEACON      WeatherMessage_Type = 2
+	WeatherMessage_AIRMET      WeatherMessage_Type = 3
+	WeatherMessage_SIGMET      WeatherMessage_Type = 4
+	WeatherMessage_WINDS_ALOFT WeatherMessage_Type = 5
+	WeatherMessage_NOTAM       WeatherMessage_Type = 6
+	WeatherMessage_TFR         WeatherMessage_Type = 7
+	WeatherMessage_BULLETIN    WeatherMessage_Type = 8
+	WeatherMessage_LIGHTNING   WeatherMessage_Type = 9
+	WeatherMessage_RADAR       WeatherMessage_Type = 10
+	WeatherMessage_DERIVED     WeatherMessage_Type = 11
+	WeatherMessage_ATIS        WeatherMessage_Type = 12
+	WeatherMessage_GAIRMET     WeatherMessage_Type = 13
+	WeatherMessage_EMERGENCY   WeatherMessage_Type = 14
 )
 
 var WeatherMessage_Type_name = map[int32]string{
-	0: "METAR",
-	1: "TAF",
-	2: "BEACON",
+	0:  "METAR",
+	1:  "TAF",
+	2:  "BEACON",
+	3:  "AIRMET",
+	4:  "SIGMET",
+	5:  "WINDS_ALOFT",
+	6:  "NOTAM",
+	7:  "TFR",
+	8:  "BULLETIN",
+	9:  "LIGHTNING",
+	10: "RADAR",
+	11: "DERIVED",
+	12: "ATIS",
+	13: "GAIRMET",
+	14: "EMERGENCY",
 }
 var WeatherMessage_Type_value = map[string]int32{
-	"METAR":  0,
-	"TAF":    1,
-	"BEACON": 2,
+	"METAR":       0,
+	"TAF":         1,
+	"BEACON":      2,
+	"AIRMET":      3,
+	"SIGMET":      4,
+	"WINDS_ALOFT": 5,
+	"NOTAM":       6,
+	"TFR":         7,
+	"BULLETIN":    8,
+	"LIGHTNING":   9,
+	"RADAR":       10,
+	"DERIVED":     11,
+	"ATIS":        12,
+	"GAIRMET":     13,
+	"EMERGENCY":   14,
 }
 
 func (x WeatherMessage_Type) String() string {
 	return proto.EnumName(WeatherMessage_Type_name, int32(x))
 }
-func (WeatherMessage_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1, 0} }
+func (WeatherMessage_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
 
 type ServerStatus struct {
 	TimeOk                 bool     `protobuf:"varint,1,opt,name=time_ok,json=timeOk" json:"time_ok,omitempty"`
@@ -63,6 +174,20 @@ type ServerStatus struct {
 	FreqSchemeCurrentIndex uint32   `protobuf:"varint,8,opt,name=freq_scheme_current_index,json=freqSchemeCurrentIndex" json:"freq_scheme_current_index,omitempty"`
 	FreqBandStart          uint32   `protobuf:"varint,9,opt,name=freq_band_start,json=freqBandStart" json:"freq_band_start,omitempty"`
 	FreqBandEnd            uint32   `protobuf:"varint,10,opt,name=freq_band_end,json=freqBandEnd" json:"freq_band_end,omitempty"`
+	AdvisoriesTracked      uint32   `protobuf:"varint,11,opt,name=advisories_tracked,json=advisoriesTracked" json:"advisories_tracked,omitempty"`
+	NotamsTracked          uint32   `protobuf:"varint,12,opt,name=notams_tracked,json=notamsTracked" json:"notams_tracked,omitempty"`
+	TfrsTracked            uint32   `protobuf:"varint,13,opt,name=tfrs_tracked,json=tfrsTracked" json:"tfrs_tracked,omitempty"`
+	AirtimeUsed            uint32   `protobuf:"varint,14,opt,name=airtime_used,json=airtimeUsed" json:"airtime_used,omitempty"`
+	DutyCycleLimit         uint32   `protobuf:"varint,15,opt,name=duty_cycle_limit,json=dutyCycleLimit" json:"duty_cycle_limit,omitempty"`
+	BandPlan               string   `protobuf:"bytes,16,opt,name=band_plan,json=bandPlan" json:"band_plan,omitempty"`
+	TdmaFrame              uint32   `protobuf:"varint,17,opt,name=tdma_frame,json=tdmaFrame" json:"tdma_frame,omitempty"`
+	TdmaSlots              uint32   `protobuf:"varint,18,opt,name=tdma_slots,json=tdmaSlots" json:"tdma_slots,omitempty"`
+	TdmaSlot               uint32   `protobuf:"varint,19,opt,name=tdma_slot,json=tdmaSlot" json:"tdma_slot,omitempty"`
+	RadioIndex             uint32   `protobuf:"varint,20,opt,name=radio_index,json=radioIndex" json:"radio_index,omitempty"`
+	RadioCount             uint32   `protobuf:"varint,21,opt,name=radio_count,json=radioCount" json:"radio_count,omitempty"`
+	FreqSchemeAirtime      []uint32 `protobuf:"varint,22,rep,packed,name=freq_scheme_airtime,json=freqSchemeAirtime" json:"freq_scheme_airtime,omitempty"`
+	FreqSchemeDutyCycle    []uint32 `protobuf:"varint,23,rep,packed,name=freq_scheme_duty_cycle,json=freqSchemeDutyCycle" json:"freq_scheme_duty_cycle,omitempty"`
+	TdmaGuard              uint32   `protobuf:"varint,24,opt,name=tdma_guard,json=tdmaGuard" json:"tdma_guard,omitempty"`
 }
 
 func (m *ServerStatus) Reset()                    { *m = ServerStatus{} }
@@ -140,20 +265,258 @@ func (m *ServerStatus) GetFreqBandEnd() uint32 {
 	return 0
 }
 
+func (m *ServerStatus) GetAdvisoriesTracked() uint32 {
+	if m != nil {
+		return m.AdvisoriesTracked
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetNotamsTracked() uint32 {
+	if m != nil {
+		return m.NotamsTracked
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetTfrsTracked() uint32 {
+	if m != nil {
+		return m.TfrsTracked
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetAirtimeUsed() uint32 {
+	if m != nil {
+		return m.AirtimeUsed
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetDutyCycleLimit() uint32 {
+	if m != nil {
+		return m.DutyCycleLimit
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetBandPlan() string {
+	if m != nil {
+		return m.BandPlan
+	}
+	return ""
+}
+
+func (m *ServerStatus) GetTdmaFrame() uint32 {
+	if m != nil {
+		return m.TdmaFrame
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetTdmaSlots() uint32 {
+	if m != nil {
+		return m.TdmaSlots
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetTdmaSlot() uint32 {
+	if m != nil {
+		return m.TdmaSlot
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetRadioIndex() uint32 {
+	if m != nil {
+		return m.RadioIndex
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetRadioCount() uint32 {
+	if m != nil {
+		return m.RadioCount
+	}
+	return 0
+}
+
+func (m *ServerStatus) GetFreqSchemeAirtime() []uint32 {
+	if m != nil {
+		return m.FreqSchemeAirtime
+	}
+	return nil
+}
+
+func (m *ServerStatus) GetFreqSchemeDutyCycle() []uint32 {
+	if m != nil {
+		return m.FreqSchemeDutyCycle
+	}
+	return nil
+}
+
+func (m *ServerStatus) GetTdmaGuard() uint32 {
+	if m != nil {
+		return m.TdmaGuard
+	}
+	return 0
+}
+
+type Area struct {
+	Polygon []byte `protobuf:"bytes,1,opt,name=polygon" json:"polygon,omitempty"`
+	Floor   uint32 `protobuf:"varint,2,opt,name=floor" json:"floor,omitempty"`
+	Ceiling uint32 `protobuf:"varint,3,opt,name=ceiling" json:"ceiling,omitempty"`
+	Radius  uint32 `protobuf:"varint,4,opt,name=radius" json:"radius,omitempty"`
+}
+
+func (m *Area) Reset()                    { *m = Area{} }
+func (m *Area) String() string            { return proto.CompactTextString(m) }
+func (*Area) ProtoMessage()               {}
+func (*Area) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Area) GetPolygon() []byte {
+	if m != nil {
+		return m.Polygon
+	}
+	return nil
+}
+
+func (m *Area) GetFloor() uint32 {
+	if m != nil {
+		return m.Floor
+	}
+	return 0
+}
+
+func (m *Area) GetCeiling() uint32 {
+	if m != nil {
+		return m.Ceiling
+	}
+	return 0
+}
+
+func (m *Area) GetRadius() uint32 {
+	if m != nil {
+		return m.Radius
+	}
+	return 0
+}
+
+type Grid struct {
+	Cols      uint32 `protobuf:"varint,1,opt,name=cols" json:"cols,omitempty"`
+	Rows      uint32 `protobuf:"varint,2,opt,name=rows" json:"rows,omitempty"`
+	CellSize  uint32 `protobuf:"varint,3,opt,name=cell_size,json=cellSize" json:"cell_size,omitempty"`
+	ColOffset int32  `protobuf:"zigzag32,4,opt,name=col_offset,json=colOffset" json:"col_offset,omitempty"`
+	RowOffset int32  `protobuf:"zigzag32,5,opt,name=row_offset,json=rowOffset" json:"row_offset,omitempty"`
+	Cells     []byte `protobuf:"bytes,6,opt,name=cells" json:"cells,omitempty"`
+}
+
+func (m *Grid) Reset()                    { *m = Grid{} }
+func (m *Grid) String() string            { return proto.CompactTextString(m) }
+func (*Grid) ProtoMessage()               {}
+func (*Grid) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Grid) GetCols() uint32 {
+	if m != nil {
+		return m.Cols
+	}
+	return 0
+}
+
+func (m *Grid) GetRows() uint32 {
+	if m != nil {
+		return m.Rows
+	}
+	return 0
+}
+
+func (m *Grid) GetCellSize() uint32 {
+	if m != nil {
+		return m.CellSize
+	}
+	return 0
+}
+
+func (m *Grid) GetColOffset() int32 {
+	if m != nil {
+		return m.ColOffset
+	}
+	return 0
+}
+
+func (m *Grid) GetRowOffset() int32 {
+	if m != nil {
+		return m.RowOffset
+	}
+	return 0
+}
+
+func (m *Grid) GetCells() []byte {
+	if m != nil {
+		return m.Cells
+	}
+	return nil
+}
+
+type Derived struct {
+	FlightCategory     Derived_FlightCategory `protobuf:"varint,1,opt,name=flight_category,json=flightCategory,enum=txwx.Derived_FlightCategory" json:"flight_category,omitempty"`
+	DensityAltitude    int32                  `protobuf:"zigzag32,2,opt,name=density_altitude,json=densityAltitude" json:"density_altitude,omitempty"`
+	HasDensityAltitude bool                   `protobuf:"varint,3,opt,name=has_density_altitude,json=hasDensityAltitude" json:"has_density_altitude,omitempty"`
+}
+
+func (m *Derived) Reset()                    { *m = Derived{} }
+func (m *Derived) String() string            { return proto.CompactTextString(m) }
+func (*Derived) ProtoMessage()               {}
+func (*Derived) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Derived) GetFlightCategory() Derived_FlightCategory {
+	if m != nil {
+		return m.FlightCategory
+	}
+	return Derived_UNKNOWN
+}
+
+func (m *Derived) GetDensityAltitude() int32 {
+	if m != nil {
+		return m.DensityAltitude
+	}
+	return 0
+}
+
+func (m *Derived) GetHasDensityAltitude() bool {
+	if m != nil {
+		return m.HasDensityAltitude
+	}
+	return false
+}
+
 type WeatherMessage struct {
 	Type            WeatherMessage_Type `protobuf:"varint,1,opt,name=type,enum=txwx.WeatherMessage_Type" json:"type,omitempty"`
 	TxTime          uint32              `protobuf:"varint,2,opt,name=tx_time,json=txTime" json:"tx_time,omitempty"`
 	StationLat      float32             `protobuf:"fixed32,3,opt,name=station_lat,json=stationLat" json:"station_lat,omitempty"`
 	StationLng      float32             `protobuf:"fixed32,4,opt,name=station_lng,json=stationLng" json:"station_lng,omitempty"`
 	ObservationTime uint32              `protobuf:"varint,5,opt,name=observation_time,json=observationTime" json:"observation_time,omitempty"`
+	ValidFrom       uint32              `protobuf:"varint,6,opt,name=valid_from,json=validFrom" json:"valid_from,omitempty"`
+	ValidTo         uint32              `protobuf:"varint,7,opt,name=valid_to,json=validTo" json:"valid_to,omitempty"`
+	Ident           string              `protobuf:"bytes,8,opt,name=ident" json:"ident,omitempty"`
+	Uncertified     bool                `protobuf:"varint,9,opt,name=uncertified" json:"uncertified,omitempty"`
 	TextData        string              `protobuf:"bytes,10,opt,name=text_data,json=textData" json:"text_data,omitempty"`
 	ServerStatus    *ServerStatus       `protobuf:"bytes,11,opt,name=server_status,json=serverStatus" json:"server_status,omitempty"`
+	Area            *Area               `protobuf:"bytes,12,opt,name=area" json:"area,omitempty"`
+	Hazard          Hazard              `protobuf:"varint,13,opt,name=hazard,enum=txwx.Hazard" json:"hazard,omitempty"`
+	Severity        uint32              `protobuf:"varint,14,opt,name=severity" json:"severity,omitempty"`
+	Winds           []byte              `protobuf:"bytes,15,opt,name=winds" json:"winds,omitempty"`
+	Grid            *Grid               `protobuf:"bytes,16,opt,name=grid" json:"grid,omitempty"`
+	Derived         *Derived            `protobuf:"bytes,17,opt,name=derived" json:"derived,omitempty"`
+	Mac             []byte              `protobuf:"bytes,18,opt,name=mac" json:"mac,omitempty"`
+	StationId       uint32              `protobuf:"fixed32,19,opt,name=station_id,json=stationId" json:"station_id,omitempty"`
 }
 
 func (m *WeatherMessage) Reset()                    { *m = WeatherMessage{} }
 func (m *WeatherMessage) String() string            { return proto.CompactTextString(m) }
 func (*WeatherMessage) ProtoMessage()               {}
-func (*WeatherMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*WeatherMessage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *WeatherMessage) GetType() WeatherMessage_Type {
 	if m != nil {
@@ -190,6 +553,34 @@ func (m *WeatherMessage) GetObservationTime() uint32 {
 	return 0
 }
 
+func (m *WeatherMessage) GetValidFrom() uint32 {
+	if m != nil {
+		return m.ValidFrom
+	}
+	return 0
+}
+
+func (m *WeatherMessage) GetValidTo() uint32 {
+	if m != nil {
+		return m.ValidTo
+	}
+	return 0
+}
+
+func (m *WeatherMessage) GetIdent() string {
+	if m != nil {
+		return m.Ident
+	}
+	return ""
+}
+
+func (m *WeatherMessage) GetUncertified() bool {
+	if m != nil {
+		return m.Uncertified
+	}
+	return false
+}
+
 func (m *WeatherMessage) GetTextData() string {
 	if m != nil {
 		return m.TextData
@@ -204,45 +595,158 @@ func (m *WeatherMessage) GetServerStatus() *ServerStatus {
 	return nil
 }
 
+func (m *WeatherMessage) GetArea() *Area {
+	if m != nil {
+		return m.Area
+	}
+	return nil
+}
+
+func (m *WeatherMessage) GetHazard() Hazard {
+	if m != nil {
+		return m.Hazard
+	}
+	return Hazard_HAZARD_NONE
+}
+
+func (m *WeatherMessage) GetSeverity() uint32 {
+	if m != nil {
+		return m.Severity
+	}
+	return 0
+}
+
+func (m *WeatherMessage) GetWinds() []byte {
+	if m != nil {
+		return m.Winds
+	}
+	return nil
+}
+
+func (m *WeatherMessage) GetGrid() *Grid {
+	if m != nil {
+		return m.Grid
+	}
+	return nil
+}
+
+func (m *WeatherMessage) GetDerived() *Derived {
+	if m != nil {
+		return m.Derived
+	}
+	return nil
+}
+
+func (m *WeatherMessage) GetMac() []byte {
+	if m != nil {
+		return m.Mac
+	}
+	return nil
+}
+
+func (m *WeatherMessage) GetStationId() uint32 {
+	if m != nil {
+		return m.StationId
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*ServerStatus)(nil), "txwx.ServerStatus")
+	proto.RegisterType((*Area)(nil), "txwx.Area")
+	proto.RegisterType((*Grid)(nil), "txwx.Grid")
+	proto.RegisterType((*Derived)(nil), "txwx.Derived")
 	proto.RegisterType((*WeatherMessage)(nil), "txwx.WeatherMessage")
+	proto.RegisterEnum("txwx.Hazard", Hazard_name, Hazard_value)
+	proto.RegisterEnum("txwx.Derived_FlightCategory", Derived_FlightCategory_name, Derived_FlightCategory_value)
 	proto.RegisterEnum("txwx.WeatherMessage_Type", WeatherMessage_Type_name, WeatherMessage_Type_value)
 }
 
 func init() { proto.RegisterFile("proto/test.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 486 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x93, 0x4f, 0x6f, 0xda, 0x40,
-	0x10, 0xc5, 0x6b, 0xfe, 0x33, 0x60, 0xe2, 0x6c, 0xa5, 0xd6, 0x51, 0x0f, 0xa5, 0x48, 0x8d, 0xa8,
-	0x94, 0x52, 0x89, 0x1e, 0xaa, 0x1e, 0x21, 0xa1, 0x52, 0xa5, 0x50, 0x24, 0x43, 0xd5, 0xe3, 0x6a,
-	0x61, 0x27, 0xc4, 0x02, 0xdb, 0x74, 0x77, 0x08, 0xce, 0x37, 0xeb, 0xbd, 0x5f, 0x2c, 0xda, 0xdd,
-	0x24, 0x38, 0x37, 0xef, 0x7b, 0xbf, 0xd1, 0x78, 0xe6, 0xed, 0x42, 0xb0, 0x53, 0x19, 0x65, 0x5f,
-	0x08, 0x35, 0x0d, 0xec, 0x27, 0xab, 0x50, 0x7e, 0xc8, 0x7b, 0xff, 0xca, 0xd0, 0x9e, 0xa3, 0xba,
-	0x43, 0x35, 0x27, 0x41, 0x7b, 0xcd, 0xde, 0x42, 0x9d, 0xe2, 0x04, 0x79, 0xb6, 0x09, 0xbd, 0xae,
-	0xd7, 0x6f, 0x44, 0x35, 0x73, 0x9c, 0x6d, 0xd8, 0x05, 0xb0, 0x03, 0x0a, 0xba, 0x45, 0xc5, 0xf7,
-	0x3b, 0x29, 0x08, 0xb5, 0x61, 0x4a, 0x96, 0x09, 0x1e, 0x9d, 0xdf, 0xce, 0x98, 0x6d, 0xd8, 0x47,
-	0xe8, 0x24, 0x48, 0x42, 0x69, 0x4e, 0x4a, 0xac, 0x36, 0x28, 0xc3, 0x72, 0xd7, 0xeb, 0xfb, 0x91,
-	0xef, 0xd4, 0x85, 0x13, 0xd9, 0x07, 0x68, 0x93, 0xb8, 0x39, 0x42, 0x15, 0x0b, 0xb5, 0x8c, 0xf6,
-	0x84, 0x5c, 0x40, 0x70, 0xa3, 0xf0, 0x2f, 0xd7, 0xab, 0x5b, 0x4c, 0x90, 0x6f, 0x63, 0x4d, 0x61,
-	0xb5, 0x5b, 0xee, 0xfb, 0xe3, 0x52, 0xe0, 0x45, 0x1d, 0xe3, 0xcd, 0xad, 0x75, 0x1d, 0x6b, 0x62,
-	0x03, 0x38, 0x2d, 0xd2, 0xf2, 0x80, 0xdb, 0x6d, 0x58, 0x7b, 0xc6, 0x4f, 0x8e, 0xf8, 0x95, 0xb1,
-	0xd8, 0x10, 0x5e, 0x17, 0xf9, 0x24, 0x93, 0x49, 0x26, 0x31, 0xac, 0x3f, 0x57, 0x9c, 0x1e, 0x2b,
-	0xa6, 0xce, 0x64, 0xdf, 0xe1, 0xac, 0x58, 0xb3, 0xda, 0x2b, 0x85, 0x29, 0xf1, 0x38, 0x95, 0x98,
-	0x87, 0x0d, 0x3b, 0xc1, 0x9b, 0x63, 0xd5, 0xa5, 0xb3, 0x7f, 0x1a, 0x97, 0x9d, 0x83, 0xfd, 0x03,
-	0xbe, 0x14, 0xa9, 0xe4, 0x9a, 0x84, 0xa2, 0xb0, 0xe9, 0xf6, 0x62, 0xe4, 0xb1, 0x48, 0xe5, 0xdc,
-	0x88, 0xac, 0x07, 0xfe, 0x91, 0xc3, 0x54, 0x86, 0xe0, 0x16, 0xf3, 0x44, 0x4d, 0x52, 0xd9, 0xfb,
-	0x5f, 0x82, 0xce, 0x1f, 0xb7, 0xf7, 0x29, 0x6a, 0x2d, 0xd6, 0xc8, 0x3e, 0x43, 0x85, 0xee, 0x77,
-	0x68, 0x93, 0xeb, 0x0c, 0xcf, 0x06, 0x26, 0xe2, 0xc1, 0x4b, 0x66, 0xb0, 0xb8, 0xdf, 0x61, 0x64,
-	0x31, 0x9b, 0x75, 0xce, 0x4d, 0xbe, 0x36, 0x47, 0x3f, 0xaa, 0x51, 0xbe, 0x88, 0x13, 0x64, 0xef,
-	0xa1, 0xa5, 0x49, 0x50, 0x9c, 0xa5, 0x7c, 0x2b, 0xc8, 0x46, 0x57, 0x8a, 0xe0, 0x51, 0xba, 0x16,
-	0xf4, 0x02, 0x48, 0xd7, 0x36, 0xb6, 0x02, 0x90, 0xae, 0xd9, 0x27, 0x08, 0xb2, 0xa5, 0x46, 0x75,
-	0xe7, 0x20, 0xdb, 0xa3, 0x6a, 0x7b, 0x9c, 0x14, 0x74, 0xdb, 0xec, 0x1d, 0x34, 0x09, 0x73, 0xe2,
-	0x52, 0x90, 0xb0, 0x73, 0x36, 0xa3, 0x86, 0x11, 0xae, 0x04, 0x09, 0xf6, 0x0d, 0x7c, 0x6d, 0xaf,
-	0xa7, 0xd9, 0x16, 0xed, 0x75, 0xd8, 0xea, 0x7a, 0xfd, 0xd6, 0x90, 0xb9, 0xd1, 0x8a, 0x37, 0x37,
-	0x6a, 0xeb, 0xc2, 0xa9, 0x77, 0x0e, 0x15, 0x33, 0x29, 0x6b, 0x42, 0x75, 0x3a, 0x59, 0x8c, 0xa2,
-	0xe0, 0x15, 0xab, 0x43, 0x79, 0x31, 0xfa, 0x11, 0x78, 0x0c, 0xa0, 0x36, 0x9e, 0x8c, 0x2e, 0x67,
-	0xbf, 0x82, 0xd2, 0xb2, 0x66, 0x5f, 0xc3, 0xd7, 0x87, 0x00, 0x00, 0x00, 0xff, 0xff, 0x06, 0xb7,
-	0x3a, 0x64, 0x21, 0x03, 0x00, 0x00,
+	// 1314 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x56, 0xdb, 0x72, 0x23, 0xb5,
+	0x16, 0x1d, 0xc7, 0xf7, 0xed, 0x4b, 0x3a, 0x9a, 0x9c, 0x4c, 0xcf, 0x39, 0x67, 0x98, 0xe0, 0x62,
+	0x20, 0x50, 0x33, 0x81, 0x0a, 0x0f, 0x53, 0x3c, 0x3a, 0x76, 0x3b, 0x71, 0x61, 0xb7, 0x29, 0xb9,
+	0x93, 0x14, 0xbc, 0x74, 0x69, 0x2c, 0xd9, 0xe9, 0x4a, 0xbb, 0x3b, 0x48, 0x72, 0x12, 0x4f, 0x15,
+	0xdf, 0xc2, 0x9f, 0x50, 0x3c, 0xf0, 0x45, 0x7c, 0x01, 0xb5, 0xa5, 0xf6, 0x0d, 0x78, 0xd3, 0x5e,
+	0x6b, 0x49, 0xbb, 0xf7, 0xd5, 0x06, 0xe7, 0x5e, 0xa6, 0x3a, 0xfd, 0x5a, 0x0b, 0xa5, 0x4f, 0xcd,
+	0x91, 0x14, 0xf4, 0xd3, 0xe3, 0x53, 0xeb, 0x8f, 0x32, 0xd4, 0xc7, 0x42, 0x3e, 0x08, 0x39, 0xd6,
+	0x4c, 0x2f, 0x14, 0x79, 0x01, 0x65, 0x1d, 0xcd, 0x45, 0x98, 0xde, 0xb9, 0xb9, 0xe3, 0xdc, 0x49,
+	0x85, 0x96, 0xd0, 0x1c, 0xdd, 0x91, 0xb7, 0x40, 0x1e, 0x05, 0xd3, 0xb7, 0x42, 0x86, 0x8b, 0x7b,
+	0xce, 0xb4, 0x50, 0xa8, 0xd9, 0x33, 0x1a, 0x27, 0x63, 0xae, 0x2c, 0x31, 0xba, 0x23, 0x6f, 0xa0,
+	0x39, 0x17, 0x9a, 0x49, 0x15, 0x6a, 0xc9, 0x26, 0x77, 0x82, 0xbb, 0xf9, 0xe3, 0xdc, 0x49, 0x83,
+	0x36, 0x2c, 0x1a, 0x58, 0x90, 0x7c, 0x0a, 0x75, 0xcd, 0xa6, 0x1b, 0x51, 0xc1, 0x88, 0x6a, 0x88,
+	0xad, 0x24, 0x6f, 0xc1, 0x99, 0x4a, 0xf1, 0x73, 0xa8, 0x26, 0xb7, 0x62, 0x2e, 0xc2, 0x38, 0x52,
+	0xda, 0x2d, 0x1e, 0xe7, 0x4f, 0x1a, 0xe7, 0x7b, 0x4e, 0x8e, 0x36, 0x91, 0x1b, 0x1b, 0x6a, 0x10,
+	0x29, 0x4d, 0x4e, 0xe1, 0x60, 0x5b, 0xcd, 0x1f, 0x45, 0x1c, 0xbb, 0xa5, 0xb5, 0x7c, 0x7f, 0x23,
+	0xef, 0x22, 0x45, 0xce, 0xe0, 0xf9, 0xb6, 0x7e, 0x9e, 0xf2, 0x79, 0xca, 0x85, 0x5b, 0x5e, 0xdf,
+	0x38, 0xd8, 0xdc, 0x18, 0x5a, 0x92, 0x7c, 0x07, 0x2f, 0xb7, 0xef, 0x4c, 0x16, 0x52, 0x8a, 0x44,
+	0x87, 0x51, 0xc2, 0xc5, 0x93, 0x5b, 0x31, 0x11, 0x1c, 0x6d, 0x6e, 0x75, 0x2c, 0xdd, 0x47, 0x96,
+	0x7c, 0x0e, 0xe6, 0x0b, 0xc2, 0x0f, 0x2c, 0xe1, 0xa1, 0xd2, 0x4c, 0x6a, 0xb7, 0x6a, 0xf3, 0x82,
+	0xf0, 0x39, 0x4b, 0xf8, 0x18, 0x41, 0xd2, 0x82, 0xc6, 0x46, 0x27, 0x12, 0xee, 0x82, 0x4d, 0xcc,
+	0x4a, 0xe5, 0x25, 0x9c, 0xbc, 0x03, 0xc2, 0xf8, 0x43, 0xa4, 0x52, 0x19, 0x89, 0x4d, 0x06, 0x6b,
+	0x46, 0x78, 0xb0, 0x61, 0x56, 0x79, 0x7c, 0x03, 0xcd, 0x24, 0xd5, 0x6c, 0xbe, 0x91, 0xd6, 0xad,
+	0x67, 0x8b, 0x6e, 0x57, 0x64, 0xba, 0x55, 0xb6, 0x46, 0x56, 0x91, 0xe9, 0x4e, 0xd1, 0x58, 0x24,
+	0x4d, 0x97, 0x2c, 0x94, 0xe0, 0x6e, 0xd3, 0x4a, 0x32, 0xec, 0x4a, 0x09, 0x4e, 0x4e, 0xc0, 0xe1,
+	0x0b, 0xbd, 0x0c, 0x27, 0xcb, 0x49, 0x8c, 0x35, 0x9b, 0x47, 0xda, 0xdd, 0x37, 0xb2, 0x26, 0xe2,
+	0x1d, 0x84, 0x07, 0x88, 0x92, 0xff, 0x41, 0xd5, 0x04, 0x79, 0x1f, 0xb3, 0xc4, 0x75, 0x8e, 0x73,
+	0x27, 0x55, 0x5a, 0x41, 0xe0, 0x87, 0x98, 0x25, 0xe4, 0x15, 0x80, 0xe6, 0x73, 0x16, 0x4e, 0x25,
+	0x9b, 0x0b, 0xf7, 0xc0, 0x3c, 0x50, 0x45, 0xa4, 0x87, 0xc0, 0x9a, 0x56, 0x71, 0xaa, 0x95, 0x4b,
+	0x36, 0xf4, 0x18, 0x01, 0x7c, 0x7a, 0x4d, 0xbb, 0xcf, 0x0d, 0x5b, 0x59, 0xb1, 0xe4, 0x35, 0xd4,
+	0x24, 0xe3, 0x51, 0x9a, 0x95, 0xed, 0xd0, 0xd0, 0x60, 0x20, 0x5b, 0xaa, 0xb5, 0x60, 0x92, 0x2e,
+	0x12, 0xed, 0xfe, 0x67, 0x4b, 0xd0, 0x41, 0xe4, 0xef, 0xad, 0x93, 0x85, 0xef, 0x1e, 0xfd, 0x5b,
+	0xeb, 0xb4, 0x2d, 0x49, 0xde, 0xc3, 0xd1, 0x4e, 0x7b, 0xae, 0x73, 0xe4, 0xbe, 0x58, 0x5f, 0x7b,
+	0xbe, 0xd5, 0xa3, 0xab, 0x5c, 0xad, 0x43, 0x9d, 0x2d, 0x98, 0xe4, 0xae, 0xbb, 0x09, 0xf5, 0x02,
+	0x81, 0xd6, 0x2d, 0x14, 0xda, 0x52, 0x30, 0xe2, 0x42, 0xf9, 0x3e, 0x8d, 0x97, 0xb3, 0x34, 0x31,
+	0xd3, 0x5b, 0xa7, 0x2b, 0x93, 0x1c, 0x42, 0x71, 0x1a, 0xa7, 0xa9, 0x34, 0x13, 0xdb, 0xa0, 0xd6,
+	0x40, 0xfd, 0x44, 0x44, 0x71, 0x94, 0xcc, 0xb2, 0xf9, 0x5c, 0x99, 0xe4, 0x08, 0x4a, 0x18, 0xeb,
+	0x42, 0x65, 0x33, 0x99, 0x59, 0xad, 0x5f, 0x73, 0x50, 0xb8, 0x90, 0x11, 0x27, 0x04, 0x0a, 0x93,
+	0x34, 0x56, 0xc6, 0x4f, 0x83, 0x9a, 0x33, 0x62, 0x32, 0x7d, 0x54, 0x99, 0x0f, 0x73, 0xc6, 0x2a,
+	0x4c, 0x44, 0x1c, 0x87, 0x2a, 0xfa, 0x28, 0x32, 0x27, 0x15, 0x04, 0xc6, 0xd1, 0x47, 0x13, 0xd6,
+	0x24, 0x8d, 0xc3, 0x74, 0x3a, 0x55, 0x42, 0x1b, 0x4f, 0x07, 0xb4, 0x3a, 0x49, 0xe3, 0x91, 0x01,
+	0x90, 0x96, 0xe9, 0xe3, 0x8a, 0x2e, 0x5a, 0x5a, 0xa6, 0x8f, 0x19, 0x7d, 0x08, 0x45, 0x7c, 0x49,
+	0xb9, 0x25, 0x13, 0xab, 0x35, 0x5a, 0x7f, 0xe6, 0xa0, 0xdc, 0x15, 0x32, 0x7a, 0x10, 0x9c, 0x78,
+	0xb0, 0x3f, 0x8d, 0xa3, 0xd9, 0xad, 0x0e, 0x27, 0x4c, 0x8b, 0x59, 0x2a, 0x97, 0xe6, 0x7b, 0x9b,
+	0x67, 0xff, 0x3f, 0xc5, 0xf5, 0x77, 0x9a, 0xe9, 0x4e, 0x7b, 0x46, 0xd4, 0xc9, 0x34, 0xb4, 0x39,
+	0xdd, 0xb1, 0xc9, 0x97, 0xe0, 0x70, 0x91, 0xa8, 0x48, 0x2f, 0x43, 0x16, 0xeb, 0x48, 0x2f, 0xb8,
+	0x30, 0x31, 0x1e, 0xd0, 0xfd, 0x0c, 0x6f, 0x67, 0x30, 0xf9, 0x06, 0x0e, 0x6f, 0x99, 0x0a, 0xff,
+	0x21, 0xcf, 0x9b, 0x45, 0x49, 0x6e, 0x99, 0xea, 0xee, 0xde, 0x68, 0x75, 0xa0, 0xb9, 0xeb, 0x9e,
+	0xd4, 0xa0, 0x7c, 0xe5, 0x7f, 0xef, 0x8f, 0x6e, 0x7c, 0xe7, 0x19, 0x29, 0x43, 0xfe, 0xba, 0x47,
+	0x9d, 0x1c, 0xa9, 0x40, 0x61, 0x88, 0xa7, 0x3d, 0x84, 0xfa, 0x3d, 0xea, 0xe4, 0x11, 0x1a, 0xe0,
+	0xa9, 0xd0, 0xfa, 0xbd, 0x04, 0xcd, 0x1b, 0xbb, 0x84, 0x87, 0x42, 0x29, 0x36, 0x13, 0xe4, 0x1d,
+	0x14, 0xf4, 0xf2, 0x5e, 0x64, 0x01, 0xbf, 0xb4, 0x01, 0xef, 0x6a, 0x4e, 0x83, 0xe5, 0xbd, 0xa0,
+	0x46, 0x66, 0x16, 0xff, 0x53, 0x68, 0x5a, 0xd8, 0x96, 0xaf, 0xa4, 0x9f, 0x02, 0xec, 0xd9, 0xd7,
+	0x50, 0x53, 0x9a, 0xe9, 0x28, 0x4d, 0xc2, 0x98, 0x69, 0x13, 0xc8, 0x1e, 0x85, 0x0c, 0x1a, 0x30,
+	0xbd, 0x23, 0x48, 0x66, 0x6e, 0x61, 0x57, 0x90, 0xcc, 0x30, 0x7d, 0xe9, 0x07, 0x25, 0xe4, 0x83,
+	0x15, 0x19, 0x1f, 0x45, 0xe3, 0x63, 0x7f, 0x0b, 0x37, 0xce, 0x5e, 0x01, 0x3c, 0xb0, 0x38, 0xe2,
+	0xe1, 0x54, 0xa6, 0x73, 0x53, 0xd7, 0x06, 0xad, 0x1a, 0xa4, 0x27, 0xd3, 0x39, 0x79, 0x09, 0x15,
+	0x4b, 0xeb, 0xd4, 0x2d, 0xdb, 0x86, 0x35, 0x76, 0x90, 0x62, 0x33, 0x44, 0x5c, 0x24, 0xda, 0x6c,
+	0xe0, 0x2a, 0xb5, 0x06, 0x39, 0x86, 0xda, 0x22, 0x99, 0x08, 0xa9, 0xa3, 0x69, 0x24, 0xb8, 0x59,
+	0xb6, 0x15, 0xba, 0x0d, 0x99, 0x2d, 0x21, 0x9e, 0x74, 0xc8, 0x99, 0x66, 0x66, 0xcd, 0x56, 0x69,
+	0x05, 0x81, 0x2e, 0xd3, 0x8c, 0xbc, 0x87, 0x86, 0x32, 0xbf, 0x8e, 0xb8, 0xac, 0xf5, 0x42, 0x99,
+	0xf5, 0x5a, 0x3b, 0x23, 0x36, 0x99, 0xdb, 0x3f, 0x9c, 0xb4, 0xae, 0xb6, 0x2c, 0xf2, 0x09, 0x14,
+	0x98, 0x14, 0xcc, 0xec, 0xd8, 0xda, 0x19, 0x58, 0x3d, 0x8e, 0x28, 0x35, 0x38, 0xf9, 0x0c, 0x4a,
+	0xb7, 0xec, 0x23, 0x93, 0x76, 0xc1, 0x36, 0xcf, 0xea, 0x56, 0x71, 0x69, 0x30, 0x9a, 0x71, 0xe4,
+	0xbf, 0x50, 0x51, 0xe2, 0x41, 0xc8, 0x48, 0x2f, 0xb3, 0x2d, 0xbb, 0xb6, 0x31, 0xde, 0xc7, 0x28,
+	0xe1, 0xca, 0xec, 0xd5, 0x3a, 0xb5, 0x06, 0xfa, 0x9d, 0xc9, 0x88, 0xbb, 0xce, 0xb6, 0x5f, 0x9c,
+	0x57, 0x6a, 0x70, 0xf2, 0x05, 0x94, 0xb9, 0xed, 0x79, 0xb3, 0x4e, 0x6b, 0x67, 0x8d, 0x9d, 0x41,
+	0xa0, 0x2b, 0x96, 0x38, 0x90, 0x9f, 0xb3, 0x89, 0x59, 0xaa, 0x75, 0x8a, 0x47, 0x2c, 0xcd, 0xaa,
+	0xcc, 0x11, 0x37, 0xfb, 0xb4, 0x4c, 0xab, 0x19, 0xd2, 0xe7, 0xad, 0xdf, 0x72, 0x50, 0xc0, 0x76,
+	0x22, 0x55, 0x28, 0x0e, 0xbd, 0xa0, 0x4d, 0x6d, 0xef, 0x06, 0xed, 0x9e, 0x93, 0x23, 0x00, 0xa5,
+	0x73, 0xaf, 0xdd, 0x19, 0xf9, 0xce, 0x1e, 0x9e, 0xdb, 0x7d, 0x3a, 0xf4, 0x02, 0x27, 0x8f, 0xe7,
+	0x71, 0xff, 0x02, 0xcf, 0x05, 0xb2, 0x0f, 0xb5, 0x9b, 0xbe, 0xdf, 0x1d, 0x87, 0xed, 0xc1, 0xa8,
+	0x17, 0x38, 0x45, 0x7c, 0xc8, 0x1f, 0x05, 0xed, 0xa1, 0x53, 0x32, 0x0f, 0xf5, 0xa8, 0x53, 0x26,
+	0x75, 0xa8, 0x9c, 0x5f, 0x0d, 0x06, 0x5e, 0xd0, 0xf7, 0x9d, 0x0a, 0x69, 0x40, 0x75, 0xd0, 0xbf,
+	0xb8, 0x0c, 0xfc, 0xbe, 0x7f, 0xe1, 0x54, 0xf1, 0x02, 0x6d, 0x77, 0xdb, 0xd4, 0x01, 0x1c, 0xa1,
+	0xae, 0x47, 0xfb, 0xd7, 0x5e, 0xd7, 0xa9, 0xe1, 0x98, 0xb4, 0x83, 0xfe, 0xd8, 0xa9, 0x23, 0x7c,
+	0x91, 0x39, 0x6f, 0xe0, 0x6d, 0x6f, 0xe8, 0xd1, 0x0b, 0xcf, 0xef, 0xfc, 0xe8, 0x34, 0xbf, 0xfa,
+	0x05, 0x4a, 0x36, 0xfd, 0xf8, 0x25, 0x97, 0xed, 0x9f, 0xda, 0xb4, 0x1b, 0xfa, 0x23, 0xdf, 0x73,
+	0x9e, 0x91, 0x26, 0x40, 0x67, 0xe4, 0x5f, 0x7b, 0x9d, 0xa0, 0x7f, 0xed, 0x39, 0x39, 0xb4, 0x83,
+	0x2b, 0x7a, 0x7e, 0x35, 0xf0, 0xfc, 0x8e, 0xe7, 0xec, 0xa1, 0xe3, 0x7e, 0x07, 0xbf, 0x21, 0xbf,
+	0x9a, 0xcd, 0x02, 0xbe, 0x3e, 0x0c, 0xfc, 0x70, 0x74, 0x3e, 0xee, 0xf8, 0x4e, 0x11, 0xf1, 0xf6,
+	0xf8, 0xd2, 0x29, 0x99, 0x99, 0x1d, 0xdc, 0x8c, 0x6d, 0x2c, 0xe3, 0x5e, 0x27, 0xc4, 0xa0, 0x9d,
+	0xca, 0x87, 0x92, 0xf9, 0x5b, 0xf6, 0xed, 0x5f, 0x03, 0x00, 0xd3, 0x99, 0x54, 0xc5, 0xaa, 0x09,
+	0x00, 0x00,
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"github.com/kellydunn/golang-geo"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	RADAR_CELLS      = 64  // Grid is RADAR_CELLS x RADAR_CELLS, centered on the station.
+	RADAR_CELL_SIZE  = 4.0 // km.
+	RADAR_TILE_CELLS = 16  // Tiles are at most RADAR_TILE_CELLS x RADAR_TILE_CELLS before splitting to fit.
+)
+
+// radarRaster is the operator supplied precipitation image: a grayscale PNG, where brighter means more
+// intense, and a sidecar "<file>.json" giving its bounds, e.g.
+//
+//	{"North": 46.0, "South": 42.0, "West": -84.0, "East": -79.0}
+type radarRaster struct {
+	Image image.Image
+	North float64
+	South float64
+	West  float64
+	East  float64
+	Time  time.Time
+}
+
+func loadRadarRaster(fn string) (*radarRaster, error) {
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return nil, err
+	}
+	fp, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	img, err := png.Decode(fp)
+	if err != nil {
+		return nil, err
+	}
+	r := &radarRaster{Image: img, Time: fi.ModTime()}
+	buf, err := ioutil.ReadFile(fn + ".json")
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(buf, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Intensity samples the raster at p, scaled to 0-GRID_MAX_VALUE. Points off the image read as 0.
+func (r *radarRaster) Intensity(p *geo.Point) byte {
+	b := r.Image.Bounds()
+	fx := (p.Lng() - r.West) / (r.East - r.West)
+	fy := (r.North - p.Lat()) / (r.North - r.South)
+	if fx < 0 || fx >= 1 || fy < 0 || fy >= 1 {
+		return 0
+	}
+	x := b.Min.X + int(math.Floor(fx*float64(b.Dx())))
+	y := b.Min.Y + int(math.Floor(fy*float64(b.Dy())))
+	return color.GrayModel.Convert(r.Image.At(x, y)).(color.Gray).Y >> 4
+}
+
+// radarTiles samples the raster on the grid around center and cuts it into packet sized tiles.
+func radarTiles(r *radarRaster, center *geo.Point) []gridTile {
+	var tiles []gridTile
+	for tr := 0; tr < RADAR_CELLS; tr += RADAR_TILE_CELLS {
+		for tc := 0; tc < RADAR_CELLS; tc += RADAR_TILE_CELLS {
+			t := gridTile{
+				ColOffset: tc - RADAR_CELLS/2,
+				RowOffset: tr - RADAR_CELLS/2,
+				Cols:      RADAR_TILE_CELLS,
+				Rows:      RADAR_TILE_CELLS,
+				Cells:     make([]byte, RADAR_TILE_CELLS*RADAR_TILE_CELLS),
+			}
+			for i := range t.Cells {
+				p := gridCellCenter(center, RADAR_CELL_SIZE, t.RowOffset+i/t.Cols, t.ColOffset+i%t.Cols)
+				t.Cells[i] = r.Intensity(p)
+			}
+			tiles = append(tiles, splitGridTiles(t)...)
+		}
+	}
+	return tiles
+}
+
+func createRadarWeatherMessage(t gridTile, observationTime time.Time) *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_RADAR,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(observationTime.Unix()),
+		Grid: &txwx.Grid{
+			Cols:      uint32(t.Cols),
+			Rows:      uint32(t.Rows),
+			CellSize:  uint32(RADAR_CELL_SIZE * 1000),
+			ColOffset: int32(t.ColOffset),
+			RowOffset: int32(t.RowOffset),
+			Cells:     encodeGridRLE(t.Cells),
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/kellydunn/golang-geo"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testRaster covers 42-46N, 84-79W with a 10x4 image, half a degree of longitude by a degree of latitude
+// per pixel. The image bounds don't start at 0.
+func testRaster(fill func(x, y int) uint8) *radarRaster {
+	img := image.NewGray(image.Rect(5, 5, 15, 9))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 10; x++ {
+			img.SetGray(5+x, 5+y, color.Gray{Y: fill(x, y)})
+		}
+	}
+	return &radarRaster{Image: img, North: 46, South: 42, West: -84, East: -79}
+}
+
+func TestRadarIntensity(t *testing.T) {
+	r := testRaster(func(x, y int) uint8 {
+		switch {
+		case x == 0 && y == 0:
+			return 255
+		case x == 9 && y == 3:
+			return 0x7f
+		}
+		return 0x10
+	})
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     byte
+	}{
+		{"northwest corner", 46, -84, 15},
+		{"inside the first pixel", 45.5, -83.75, 15},
+		{"southeast pixel", 42.5, -79.25, 7},
+		{"middle", 44, -81.5, 1},
+		{"east edge", 44, -79, 0},
+		{"south edge", 42, -81.5, 0},
+		{"north of the image", 46.5, -81.5, 0},
+		{"west of the image", 44, -85, 0},
+	}
+	for _, tt := range tests {
+		if got := r.Intensity(geo.NewPoint(tt.lat, tt.lng)); got != tt.want {
+			t.Errorf("%s: %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRadarTiles(t *testing.T) {
+	center := geo.NewPoint(44.25, -81.60)
+	tests := []struct {
+		name  string
+		fill  func(x, y int) uint8
+		tiles int // 0 = not checked.
+		sum   int
+	}{
+		{"clear", func(x, y int) uint8 { return 0 }, 16, 0},
+		{"overcast", func(x, y int) uint8 { return 255 }, 16, RADAR_CELLS * RADAR_CELLS * GRID_MAX_VALUE},
+		{"showers", func(x, y int) uint8 { return uint8((x+y)%2) * 255 }, 0, -1},
+	}
+	for _, tt := range tests {
+		tiles := radarTiles(testRaster(tt.fill), center)
+		if tt.tiles > 0 && len(tiles) != tt.tiles {
+			t.Errorf("%s: %d tiles, want %d", tt.name, len(tiles), tt.tiles)
+		}
+		cells, sum := 0, 0
+		seen := make(map[[2]int]bool)
+		for _, tile := range tiles {
+			if n := len(encodeGridRLE(tile.Cells)); n > MAX_GRID_BYTES && len(tile.Cells) > 1 {
+				t.Errorf("%s: tile %dx%d%+d%+d encodes to %d bytes", tt.name, tile.Cols, tile.Rows, tile.ColOffset, tile.RowOffset, n)
+			}
+			for i, v := range tile.Cells {
+				key := [2]int{tile.RowOffset + i/tile.Cols, tile.ColOffset + i%tile.Cols}
+				if seen[key] {
+					t.Errorf("%s: cell %v in two tiles", tt.name, key)
+				}
+				seen[key] = true
+				cells++
+				sum += int(v)
+			}
+		}
+		if cells != RADAR_CELLS*RADAR_CELLS {
+			t.Errorf("%s: tiles cover %d cells", tt.name, cells)
+		}
+		if tt.sum >= 0 && sum != tt.sum {
+			t.Errorf("%s: intensities add up to %d, want %d", tt.name, sum, tt.sum)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"./proto"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	uatradio "../gouatradio"
+)
+
+const (
+	RADIO_QUEUE_LEN = 16 // Carousel messages and bulletins waiting per radio before txWeatherMessage blocks.
+)
+
+// txPacket is an encoded message waiting for its radio.
+type txPacket struct {
+	Data []byte
+	Type txwx.WeatherMessage_Type
+}
+
+// txRadio is one TX dongle with its own hop schedule, products and queue.
+type txRadio struct {
+	Index  int
+	Config RadioConfig
+	Radio  *uatradio.UATRadio
+	queue  *txQueue
+
+	tunedFreq    float64
+	tunedModMode byte
+
+	MessagesSent uint64        // Atomic.
+	Airtime      time.Duration // Total on the air. Only touched by the radio's goroutine.
+}
+
+var txRadios []*txRadio
+
+// radioConfigs are the configured radios, or the single radio of the top level settings.
+func radioConfigs() []RadioConfig {
+	if len(globalSettings.Radios) > 0 {
+		return globalSettings.Radios
+	}
+	return []RadioConfig{{
+		Name:         "tx",
+		ProductID:    productID,
+		Freq:         globalSettings.Freq,
+		RadioModMode: globalSettings.RadioModMode,
+		FreqScheme:   globalSettings.FreqScheme,
+	}}
+}
+
+// openRadios opens every configured dongle and starts its transmit loop.
+func openRadios() error {
+	for i, c := range radioConfigs() {
+		u, err := uatradio.NewUATRadio(c.Freq, c.RadioModMode, c.ProductID)
+		if err != nil {
+			return fmt.Errorf("radio %s: %s", c.Name, err.Error())
+		}
+		r := &txRadio{
+			Index:        i,
+			Config:       c,
+			Radio:        u,
+			queue:        newTxQueue(RADIO_QUEUE_LEN),
+			tunedFreq:    c.Freq,
+			tunedModMode: c.RadioModMode,
+		}
+		txRadios = append(txRadios, r)
+		go r.run()
+	}
+	return nil
+}
+
+func (c RadioConfig) scheme() []FreqSchemeEntry {
+	if len(c.FreqScheme) > 0 {
+		return c.FreqScheme
+	}
+	return []FreqSchemeEntry{{Freq: c.Freq, Dwell: DEFAULT_DWELL, ModMode: c.RadioModMode}}
+}
+
+func (r *txRadio) scheme() []FreqSchemeEntry {
+	return r.Config.scheme()
+}
+
+func (c RadioConfig) carries(t txwx.WeatherMessage_Type) bool {
+	for _, p := range c.Products {
+		if p == t.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// radioIndexesFor picks the radios a message goes out on: the radio listing its product, else the first
+// radio without a product list, else the first radio. Emergencies go out on all of them.
+func radioIndexesFor(configs []RadioConfig, t txwx.WeatherMessage_Type) []int {
+	if t == txwx.WeatherMessage_EMERGENCY {
+		ret := make([]int, len(configs))
+		for i := range configs {
+			ret[i] = i
+		}
+		return ret
+	}
+	for i, c := range configs {
+		if c.carries(t) {
+			return []int{i}
+		}
+	}
+	for i, c := range configs {
+		if len(c.Products) == 0 {
+			return []int{i}
+		}
+	}
+	return []int{0}
+}
+
+func radiosFor(t txwx.WeatherMessage_Type) []*txRadio {
+	if t == txwx.WeatherMessage_EMERGENCY {
+		return txRadios
+	}
+	configs := make([]RadioConfig, len(txRadios))
+	for i, r := range txRadios {
+		configs[i] = r.Config
+	}
+	var ret []*txRadio
+	for _, i := range radioIndexesFor(configs, t) {
+		ret = append(ret, txRadios[i])
+	}
+	return ret
+}
+
+// sends checks if radio is one of the radios t goes out on.
+func (radio *txRadio) sends(t txwx.WeatherMessage_Type) bool {
+	for _, r := range radiosFor(t) {
+		if r == radio {
+			return true
+		}
+	}
+	return false
+}
+
+// run transmits the radio's queue, keeping to the hop schedule, TDMA slot, listen-before-talk and
+// duty cycle.
+func (r *txRadio) run() {
+	for {
+		p := r.queue.pop()
+		if priorityFor(p.Type) > PRIORITY_BEACON && emergencyActive(sysClock.Now()) {
+			// Queued before the emergency started.
+			atomic.AddUint64(&globalStatus.MessagesPreempted, 1)
+			continue
+		}
+		err := r.transmit(p)
+		if err != nil {
+			log.Printf("radio %s: %s\n", r.Config.Name, err.Error())
+		}
+	}
+}
+
+// tune moves the radio to freq and modMode, if it isn't there already.
+func (r *txRadio) tune(freq float64, modMode byte) error {
+	if freq != r.tunedFreq {
+		err := r.Radio.SetFrequency(freq)
+		if err != nil {
+			log.Printf("radio %s: can't retune to %0.3f MHz: %s\n", r.Config.Name, freq, err.Error())
+			return err
+		}
+		r.tunedFreq = freq
+	}
+	if modMode != r.tunedModMode {
+		err := r.Radio.SetModMode(modMode)
+		if err != nil {
+			log.Printf("radio %s: can't switch to modulation mode %d: %s\n", r.Config.Name, modMode, err.Error())
+			return err
+		}
+		r.tunedModMode = modMode
+	}
+	return nil
+}
+
+// inModeSlot checks if a message of type t may go out in a hop slot of mode slotMode. Types with a
+// ProductModModes entry wait for a slot of their mode, so the advertised schedule tells receivers
+// which mode to listen in at any time.
+func inModeSlot(t txwx.WeatherMessage_Type, slotMode byte) bool {
+	m, ok := globalSettings.ProductModModes[t.String()]
+	return !ok || m == slotMode
+}
+
+func (r *txRadio) transmit(p txPacket) error {
+	deferrals := 0
+	for {
+		slot := currentHop(r.scheme(), gpsNow())
+		if !inModeSlot(p.Type, slot.Entry.ModMode) {
+			sysClock.Sleep(slot.Remaining)
+			continue
+		}
+		modMode := slot.Entry.ModMode
+		airtime := packetAirtime(modMode, len(p.Data))
+		if tdmaEnabled() {
+			if airtime > tdmaSlotTime() {
+				return fmt.Errorf("dropped a %s of %d bytes, it takes %s on the air and the TDMA slot leaves %s", p.Type, len(p.Data), airtime, tdmaSlotTime())
+			}
+			open, remaining, wait := tdmaWindow(gpsNow())
+			if !open {
+				sysClock.Sleep(wait)
+				continue
+			}
+			if remaining < airtime {
+				sysClock.Sleep(remaining) // Wait out the rest of the slot, the packet won't fit.
+				continue
+			}
+		}
+		if slot.Remaining < airtime && airtime < time.Duration(slot.Entry.Dwell)*time.Millisecond {
+			// Don't straddle a hop, receivers will have moved on.
+			sysClock.Sleep(slot.Remaining)
+			continue
+		}
+		band, ok := currentBandPlan().subBandOf(slot.Entry.Freq)
+		if !ok {
+			return fmt.Errorf("%0.3f MHz is out of band", slot.Entry.Freq)
+		}
+		err := r.tune(slot.Entry.Freq, modMode)
+		if err != nil {
+			return err
+		}
+		if channelBusy(r.Radio, slot.Entry.Freq) {
+			deferrals++
+			lbtDeferred()
+			if deferrals > globalSettings.LBTMaxDeferrals {
+				lbtGaveUp()
+				return errChannelBusy
+			}
+			sysClock.Sleep(lbtBackoff())
+			continue
+		}
+		wait := txLimiter.reserve(band, dutyCycleFor(slot.Entry.Freq), airtime)
+		if wait > 0 {
+			// This channel's duty cycle is used up. Wait for it to refill, or for the next hop.
+			if wait > slot.Remaining {
+				wait = slot.Remaining
+			}
+			sysClock.Sleep(wait)
+			continue
+		}
+		r.Radio.TX(p.Data)
+		r.Airtime += airtime
+		atomic.AddUint64(&r.MessagesSent, 1)
+		atomic.AddUint64(&globalStatus.MessagesSent, 1)
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"./proto"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	uatradio "../gouatradio"
+)
+
+func testRadio(scheme []FreqSchemeEntry) *txRadio {
+	c := RadioConfig{Name: "test", Freq: 915.0, RadioModMode: 1, FreqScheme: scheme}
+	return &txRadio{Config: c, Radio: &uatradio.UATRadio{}, queue: newTxQueue(RADIO_QUEUE_LEN), tunedFreq: c.Freq, tunedModMode: c.RadioModMode}
+}
+
+// transmitAt runs r.transmit on the fake clock and returns when the packet went out.
+func transmitAt(t *testing.T, c *fakeClock, r *txRadio, p txPacket) time.Time {
+	done := make(chan error)
+	go func() { done <- r.transmit(p) }()
+	for i := 0; i < 100; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("transmit: %s", err.Error())
+			}
+			return c.Now()
+		default:
+		}
+		c.waitTimers(1)
+		c.advance(10 * time.Millisecond)
+	}
+	t.Fatalf("never transmitted")
+	return time.Time{}
+}
+
+func TestTransmitWaitsForTDMASlot(t *testing.T) {
+	resetSettings()
+	globalSettings.TDMAFrame = 1000
+	globalSettings.TDMASlots = 4
+	globalSettings.TDMASlot = 2
+	c := newFakeClock(time.Unix(2000, 0)) // Frame start.
+	defer useClock(c)()
+	r := testRadio(nil)
+	sent := transmitAt(t, c, r, txPacket{Data: make([]byte, 20), Type: txwx.WeatherMessage_METAR})
+	ms := sent.UnixNano() / int64(time.Millisecond) % 1000
+	if ms < 500 || ms >= 750-int64(globalSettings.TDMAGuard) {
+		t.Errorf("sent %d ms into the frame, outside slot 2", ms)
+	}
+	if atomic.LoadUint64(&r.MessagesSent) != 1 {
+		t.Errorf("MessagesSent = %d", r.MessagesSent)
+	}
+}
+
+func TestTransmitDropsWhatNeverFitsTheSlot(t *testing.T) {
+	resetSettings()
+	globalSettings.TDMAFrame = 1000
+	globalSettings.TDMASlots = 4
+	globalSettings.TDMASlot = 2
+	c := newFakeClock(time.Unix(2000, 0))
+	defer useClock(c)()
+	r := testRadio([]FreqSchemeEntry{{Freq: 915.0, Dwell: 100, ModMode: 0}})
+	done := make(chan error)
+	go func() {
+		done <- r.transmit(txPacket{Data: make([]byte, MAX_PACKET_LEN), Type: txwx.WeatherMessage_METAR})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("a 250 ms packet went out in a 230 ms slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("transmit is still waiting for a slot the packet doesn't fit")
+	}
+	if atomic.LoadUint64(&r.MessagesSent) != 0 {
+		t.Errorf("MessagesSent = %d", r.MessagesSent)
+	}
+}
+
+func TestTransmitDoesntStraddleHop(t *testing.T) {
+	resetSettings()
+	scheme := []FreqSchemeEntry{{Freq: 915.0, Dwell: 100, ModMode: 1}, {Freq: 916.0, Dwell: 100, ModMode: 1}}
+	c := newFakeClock(time.Unix(3000, 0).Add(95 * time.Millisecond)) // 5 ms left on 915.0.
+	defer useClock(c)()
+	r := testRadio(scheme)
+	sent := transmitAt(t, c, r, txPacket{Data: make([]byte, 100), Type: txwx.WeatherMessage_METAR})
+	if sent.Before(time.Unix(3000, 0).Add(100 * time.Millisecond)) {
+		t.Errorf("sent at %s, before the hop", sent)
+	}
+	if r.tunedFreq != 916.0 {
+		t.Errorf("sent on %0.3f MHz, want 916.000", r.tunedFreq)
+	}
+}
+
+func TestRadiosFor(t *testing.T) {
+	resetSettings()
+	a := testRadio(nil)
+	a.Config.Products = []string{"METAR"}
+	b := testRadio(nil)
+	old := txRadios
+	txRadios = []*txRadio{a, b}
+	defer func() { txRadios = old }()
+	if rs := radiosFor(txwx.WeatherMessage_METAR); len(rs) != 1 || rs[0] != a {
+		t.Errorf("METAR not on its radio")
+	}
+	if rs := radiosFor(txwx.WeatherMessage_TAF); len(rs) != 1 || rs[0] != b {
+		t.Errorf("TAF not on the radio without a product list")
+	}
+	if rs := radiosFor(txwx.WeatherMessage_EMERGENCY); len(rs) != 2 {
+		t.Errorf("emergency not on every radio")
+	}
+	if !a.sends(txwx.WeatherMessage_METAR) || a.sends(txwx.WeatherMessage_TAF) {
+		t.Errorf("sends() disagrees with radiosFor()")
+	}
+}
+
+func TestTransmitWaitsForModeSlot(t *testing.T) {
+	resetSettings()
+	globalSettings.ProductModModes = map[string]byte{"TAF": 3}
+	scheme := []FreqSchemeEntry{{Freq: 915.0, Dwell: 100, ModMode: 1}, {Freq: 915.0, Dwell: 100, ModMode: 3}}
+	start := time.Unix(3000, 0)
+	c := newFakeClock(start)
+	defer useClock(c)()
+	r := testRadio(scheme)
+	sent := transmitAt(t, c, r, txPacket{Data: make([]byte, 100), Type: txwx.WeatherMessage_METAR})
+	if sent.Sub(start) >= 100*time.Millisecond || r.tunedModMode != 1 {
+		t.Errorf("METAR sent after %s in mode %d, want right away in the slot's mode", sent.Sub(start), r.tunedModMode)
+	}
+	sent = transmitAt(t, c, r, txPacket{Data: make([]byte, 100), Type: txwx.WeatherMessage_TAF})
+	if sent.Sub(start) < 100*time.Millisecond || r.tunedModMode != 3 {
+		t.Errorf("TAF sent after %s in mode %d, want in the mode 3 slot", sent.Sub(start), r.tunedModMode)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"./proto"
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/cyoung/uatsynth"
@@ -11,7 +12,10 @@ import (
 	"github.com/kellydunn/golang-geo"
 	"hash/crc64"
 	"log"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	uatradio "../gouatradio"
@@ -19,12 +23,176 @@ import (
 )
 
 const (
-	RECEIVE_LOG = "/var/log/messages_received.log"
+	RECEIVE_LOG             = "/var/log/messages_received.log"
+	EMERGENCY_OUTPUT_REPEAT = 30 * time.Second // Emergencies arrive every few seconds. Pass them on to Stratux this often.
+	HOP_FOLLOW_TIMEOUT      = 5 * time.Minute  // Go back to the configured hop schedule when the station we follow is quiet this long.
 )
 
 type status struct {
 	MessagesReceived uint64
 	CRCErrors        uint64
+	OutOfSlot        uint64 // Messages from TDMA stations received outside their advertised slot.
+}
+
+// tdmaAssignment is the slot a station advertised in its beacon.
+type tdmaAssignment struct {
+	Frame int
+	Slots int
+	Slot  int
+	Guard int // Milliseconds.
+}
+
+var stationSlots = make(map[string]tdmaAssignment) // Keyed by stationKey().
+
+// stationKey tells stations apart by the StationID hash in their messages, or by position for those
+// without a StationID.
+func stationKey(msg *txwx.WeatherMessage) string {
+	if msg.StationId != 0 {
+		return fmt.Sprintf("%08x", msg.StationId)
+	}
+	return fmt.Sprintf("%0.4f,%0.4f", msg.StationLat, msg.StationLng)
+}
+
+// checkTDMASlot verifies a message arrived in its station's advertised slot, if the station uses TDMA.
+// A packet that ends right at the end of the slot is decoded a little after it, so the station's guard
+// time is allowed as slack.
+func checkTDMASlot(msg *txwx.WeatherMessage) {
+	key := stationKey(msg)
+	a, ok := stationSlots[key]
+	if !ok {
+		return
+	}
+	now := gpsNow()
+	slot := tdmaSlotAt(now, a.Frame, a.Slots)
+	if slot != a.Slot && tdmaSlotAt(now.Add(-time.Duration(a.Guard)*time.Millisecond), a.Frame, a.Slots) != a.Slot {
+		globalStatus.OutOfSlot++
+		log.Printf("station %s transmitted in TDMA slot %d, advertised %d.\n", key, slot, a.Slot)
+	}
+}
+
+// tunableRadio is the part of the dongle the hop follower needs.
+type tunableRadio interface {
+	SetFrequency(freq float64) error
+	SetModMode(modMode byte) error
+}
+
+// hopFollower keeps the receiver on the frequency and modulation mode of the hop slot that the station
+// it follows is in. It starts on the configured FreqScheme (or Freq) and takes over the schedule of the
+// first station it hears a beacon from. Both sides find the slot from GPS time, see currentHop().
+type hopFollower struct {
+	mu         *sync.Mutex
+	radio      tunableRadio
+	station    string // Station whose schedule we follow, "" = the configured one.
+	scheme     []FreqSchemeEntry
+	lastBeacon time.Time
+	changed    chan struct{}
+
+	tunedFreq    float64
+	tunedModMode byte
+}
+
+func newHopFollower(radio tunableRadio) *hopFollower {
+	return &hopFollower{
+		mu:           &sync.Mutex{},
+		radio:        radio,
+		changed:      make(chan struct{}, 1),
+		tunedFreq:    globalSettings.Freq,
+		tunedModMode: globalSettings.RadioModMode,
+	}
+}
+
+// configuredScheme is the schedule from the settings file, or the single slot on Freq.
+func configuredScheme() []FreqSchemeEntry {
+	if len(globalSettings.FreqScheme) > 0 {
+		return globalSettings.FreqScheme
+	}
+	return []FreqSchemeEntry{{Freq: globalSettings.Freq, Dwell: DEFAULT_DWELL, ModMode: globalSettings.RadioModMode}}
+}
+
+// beaconScheme decodes the hop schedule a station advertises in its beacon.
+func beaconScheme(s *txwx.ServerStatus) []FreqSchemeEntry {
+	var scheme []FreqSchemeEntry
+	for i, f := range s.FreqSchemeList {
+		e := FreqSchemeEntry{Freq: float64(s.FreqBandStart) + float64(f)/65536, ModMode: globalSettings.RadioModMode}
+		if i < len(s.FreqSchemeDwell) {
+			e.Dwell = int(s.FreqSchemeDwell[i])
+		}
+		if i < len(s.FreqSchemeModmode) {
+			e.ModMode = byte(s.FreqSchemeModmode[i])
+		}
+		scheme = append(scheme, e)
+	}
+	return scheme
+}
+
+// current is the schedule being followed.
+func (f *hopFollower) current() []FreqSchemeEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.station != "" && sysClock.Now().Sub(f.lastBeacon) > HOP_FOLLOW_TIMEOUT {
+		log.Printf("no beacon from station %s for %s, back to the configured hop schedule.\n", f.station, HOP_FOLLOW_TIMEOUT)
+		f.station = ""
+		f.scheme = nil
+	}
+	if len(f.scheme) == 0 {
+		return configuredScheme()
+	}
+	return f.scheme
+}
+
+// beacon takes over the schedule advertised by station, if it's the station we follow or we don't
+// follow any yet.
+func (f *hopFollower) beacon(station string, s *txwx.ServerStatus) {
+	scheme := beaconScheme(s)
+	if len(scheme) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.station != "" && f.station != station {
+		return
+	}
+	if f.station == "" {
+		log.Printf("following the hop schedule of station %s.\n", station)
+	}
+	f.station = station
+	f.scheme = scheme
+	f.lastBeacon = sysClock.Now()
+	select {
+	case f.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (f *hopFollower) tune(e FreqSchemeEntry) {
+	if e.Freq != f.tunedFreq {
+		err := f.radio.SetFrequency(e.Freq)
+		if err != nil {
+			log.Printf("can't retune to %0.3f MHz: %s\n", e.Freq, err.Error())
+			return
+		}
+		f.tunedFreq = e.Freq
+	}
+	if e.ModMode != f.tunedModMode {
+		err := f.radio.SetModMode(e.ModMode)
+		if err != nil {
+			log.Printf("can't switch to modulation mode %d: %s\n", e.ModMode, err.Error())
+			return
+		}
+		f.tunedModMode = e.ModMode
+	}
+}
+
+// run retunes at every hop, and right away when the schedule changes.
+func (f *hopFollower) run() {
+	for {
+		slot := currentHop(f.current(), gpsNow())
+		f.tune(slot.Entry)
+		select {
+		case <-sysClock.After(slot.Remaining):
+		case <-f.changed:
+		}
+	}
 }
 
 var txwxBuild string
@@ -36,10 +204,179 @@ var globalStatus status
 // Run options.
 var manualLat float64 // Manually entered station lat.
 var manualLng float64 // Manually entered station lng.
+var apiAddr string    // Listen address of the status API, "" = off.
+
+// receivedEmergency is an authenticated emergency notice, as reported by the status API.
+type receivedEmergency struct {
+	Ident      string
+	Text       string
+	ValidFrom  time.Time
+	ValidTo    time.Time
+	StationLat float32
+	StationLng float32
+	LastOutput time.Time `json:"-"`
+}
+
+var emergenciesMutex *sync.Mutex
+var activeEmergencies = make(map[string]*receivedEmergency) // Keyed by ident and text. Protected by emergenciesMutex.
 
 // Message logging.
 var receiveLogFp *os.File
 
+var severityNames = []string{"", "LGT", "MOD", "SEV"}
+
+// formatAltitude formats hundreds of feet MSL as it appears in advisory text: "SFC", "080", "FL240".
+func formatAltitude(alt uint32) string {
+	if alt == 0 {
+		return "SFC"
+	}
+	if alt >= 180 {
+		return fmt.Sprintf("FL%03d", alt)
+	}
+	return fmt.Sprintf("%03d", alt)
+}
+
+// advisoryText rebuilds a readable AIRMET/SIGMET from its compact form, e.g.
+// "SIGMET 45C VALID 191855/192055 CONVECTIVE SEV SFC-FL450 FROM 4425N08136W-4510N08020W-...".
+func advisoryText(msg *txwx.WeatherMessage) string {
+	validFrom := time.Unix(int64(msg.ValidFrom), 0).UTC()
+	validTo := time.Unix(int64(msg.ValidTo), 0).UTC()
+	words := []string{msg.Type.String()}
+	if len(msg.Ident) > 0 {
+		words = append(words, msg.Ident)
+	}
+	words = append(words, "VALID "+validFrom.Format("021504")+"/"+validTo.Format("021504"))
+	if msg.Hazard != txwx.Hazard_HAZARD_NONE {
+		words = append(words, msg.Hazard.String())
+	}
+	if int(msg.Severity) > 0 && int(msg.Severity) < len(severityNames) {
+		words = append(words, severityNames[msg.Severity])
+	}
+	if msg.Area != nil {
+		words = append(words, areaText(msg.Area))
+	}
+	return strings.Join(words, " ")
+}
+
+// areaText describes an area as in advisory text: "SFC-FL450 FROM 4425N08136W-...-4425N08136W", or
+// "SFC-179 10.0NM RADIUS OF 4425N08136W" for circles.
+func areaText(area *txwx.Area) string {
+	text := formatAltitude(area.Floor) + "-" + formatAltitude(area.Ceiling)
+	points, err := decodePolygon(area.Polygon)
+	if err != nil {
+		log.Printf("area: %s\n", err.Error())
+		return text
+	}
+	if len(points) == 0 {
+		return text
+	}
+	if area.Radius > 0 {
+		return fmt.Sprintf("%s %0.1fNM RADIUS OF %s", text, float64(area.Radius)/10.0, formatLatLng(points[0]))
+	}
+	vertices := make([]string, 0, len(points)+1)
+	for _, p := range points {
+		vertices = append(vertices, formatLatLng(p))
+	}
+	vertices = append(vertices, vertices[0]) // Close the ring, as in the original text.
+	return text + " FROM " + strings.Join(vertices, "-")
+}
+
+// windsAloftText renders a winds aloft message in the standard FB text format.
+func windsAloftText(msg *txwx.WeatherMessage) string {
+	levels, err := decodeWindsAloft(msg.Winds)
+	if err != nil {
+		log.Printf("winds aloft %s: %s\n", msg.Ident, err.Error())
+	}
+	return formatFBText(msg.Ident, time.Unix(int64(msg.ObservationTime), 0), levels)
+}
+
+// notamText formats a NOTAM as FIS-B NOTAM text, e.g. "NOTAM-D KXYZ 10/001 RWY 09/27 CLSD 1910191400-1910191600".
+func notamText(msg *txwx.WeatherMessage) string {
+	validFrom := time.Unix(int64(msg.ValidFrom), 0).UTC()
+	if msg.ValidFrom == 0 {
+		validFrom = time.Unix(int64(msg.ObservationTime), 0).UTC()
+	}
+	validTo := "PERM"
+	if msg.ValidTo != 0 {
+		validTo = time.Unix(int64(msg.ValidTo), 0).UTC().Format("0601021504")
+	}
+	return fmt.Sprintf("NOTAM-D %s %s %s-%s", msg.Ident, msg.TextData, validFrom.Format("0601021504"), validTo)
+}
+
+// tfrText formats a TFR as FIS-B NOTAM text, e.g.
+// "NOTAM-D TFR 9/1234 VIP MOVEMENT SFC-179 10.0NM RADIUS OF 4415N08136W 1910191400-1910192000".
+func tfrText(msg *txwx.WeatherMessage) string {
+	words := []string{"NOTAM-D TFR", msg.Ident}
+	if len(msg.TextData) > 0 {
+		words = append(words, msg.TextData)
+	}
+	if msg.Area != nil {
+		words = append(words, areaText(msg.Area))
+	}
+	validFrom := time.Unix(int64(msg.ObservationTime), 0).UTC()
+	validTo := "UFN"
+	if msg.ValidTo != 0 {
+		validTo = time.Unix(int64(msg.ValidTo), 0).UTC().Format("0601021504")
+	}
+	words = append(words, validFrom.Format("0601021504")+"-"+validTo)
+	return strings.Join(words, " ")
+}
+
+// bulletinInScope checks if this receiver is inside a bulletin's optional area.
+func bulletinInScope(msg *txwx.WeatherMessage) bool {
+	if msg.Area == nil || msg.Area.Radius == 0 || stationGeoPt == nil {
+		return true
+	}
+	points, err := decodePolygon(msg.Area.Polygon)
+	if err != nil || len(points) == 0 {
+		return true
+	}
+	return stationGeoPt.GreatCircleDistance(points[0]) <= float64(msg.Area.Radius)/10.0*KM_PER_NM
+}
+
+// decodeGridMessage unpacks the grid tile of a message, along with the grid's origin and cell size (km).
+func decodeGridMessage(msg *txwx.WeatherMessage) (gridTile, *geo.Point, float64, error) {
+	g := msg.Grid
+	t := gridTile{ColOffset: int(g.ColOffset), RowOffset: int(g.RowOffset), Cols: int(g.Cols), Rows: int(g.Rows)}
+	cells, err := decodeGridRLE(g.Cells, t.Cols*t.Rows)
+	if err != nil {
+		return t, nil, 0, err
+	}
+	t.Cells = cells
+	return t, geo.NewPoint(float64(msg.StationLat), float64(msg.StationLng)), float64(g.CellSize) / 1000.0, nil
+}
+
+// lightningText lists the cells of a lightning tile that had strikes, e.g.
+// "LIGHTNING 15KM 44.2500,-81.6000:3 44.1151,-81.4117:1".
+func lightningText(msg *txwx.WeatherMessage) (string, int) {
+	t, origin, cellSize, err := decodeGridMessage(msg)
+	if err != nil {
+		log.Printf("lightning: %s\n", err.Error())
+		return "", 0
+	}
+	words := []string{fmt.Sprintf("LIGHTNING %0.0fKM", cellSize)}
+	strikes := 0
+	for i, v := range t.Cells {
+		if v == 0 {
+			continue
+		}
+		p := gridCellCenter(origin, cellSize, t.RowOffset+i/t.Cols, t.ColOffset+i%t.Cols)
+		words = append(words, fmt.Sprintf("%0.4f,%0.4f:%d", p.Lat(), p.Lng(), v))
+		strikes += int(v)
+	}
+	return strings.Join(words, " "), strikes
+}
+
+// derivedText formats derived airport values, e.g. "DERIVED KDEN 191953Z MVFR DA 7250FT".
+func derivedText(msg *txwx.WeatherMessage) string {
+	observationTime := time.Unix(int64(msg.ObservationTime), 0).UTC()
+	text := fmt.Sprintf("DERIVED %s %s %s", msg.Ident, observationTime.Format("021504Z"), msg.Derived.FlightCategory.String())
+	if msg.Derived.HasDensityAltitude {
+		text += fmt.Sprintf(" DA %dFT", msg.Derived.DensityAltitude)
+	}
+	return text
+}
+
 func generateUATEncodedTextReportMessage(msg *txwx.WeatherMessage) {
 	// Observation time - zulu.
 	observationTime := time.Unix(int64(msg.ObservationTime), 0)
@@ -50,17 +387,43 @@ func generateUATEncodedTextReportMessage(msg *txwx.WeatherMessage) {
 	uatMsg.Lon = float64(msg.StationLng)
 	uatMsg.UTCCoupled = true
 	f := new(uatsynth.UATFrame)
+	f.Product_id = 413
 	switch msg.Type {
 	case txwx.WeatherMessage_METAR:
 		f.Text_data = []string{"METAR " + msg.TextData}
 	case txwx.WeatherMessage_TAF:
 		f.Text_data = []string{"TAF " + msg.TextData}
+	case txwx.WeatherMessage_AIRMET, txwx.WeatherMessage_SIGMET, txwx.WeatherMessage_GAIRMET:
+		f.Text_data = []string{advisoryText(msg)}
+	case txwx.WeatherMessage_WINDS_ALOFT:
+		f.Text_data = []string{windsAloftText(msg)}
+	case txwx.WeatherMessage_NOTAM:
+		f.Text_data = []string{notamText(msg)}
+		f.Product_id = 8 // FIS-B NOTAMs.
+	case txwx.WeatherMessage_TFR:
+		f.Text_data = []string{tfrText(msg)}
+		f.Product_id = 8 // FIS-B NOTAMs, TFRs are NOTAMs too.
+	case txwx.WeatherMessage_BULLETIN:
+		f.Text_data = []string{"BULLETIN " + msg.Ident + " " + msg.TextData}
+	case txwx.WeatherMessage_DERIVED:
+		f.Text_data = []string{derivedText(msg)}
+	case txwx.WeatherMessage_EMERGENCY:
+		f.Text_data = []string{"URGENT EMERGENCY " + msg.Ident + " " + msg.TextData}
+	case txwx.WeatherMessage_ATIS:
+		f.Text_data = []string{"ATIS " + msg.Ident + " " + msg.TextData}
+	case txwx.WeatherMessage_LIGHTNING:
+		text, _ := lightningText(msg)
+		f.Text_data = []string{text}
 	}
 	f.FISB_hours = uint32(observationTime.Hour())
 	f.FISB_minutes = uint32(observationTime.Minute())
-	f.Product_id = 413
 	f.Frame_type = 0
 	uatMsg.Frames = append(uatMsg.Frames, f)
+	outputUATMsg(&uatMsg)
+}
+
+// outputUATMsg encodes the uplink and prints it for Stratux, in the same format as dump978.
+func outputUATMsg(uatMsg *uatsynth.UATMsg) {
 	encodedMessages, err := uatMsg.EncodeUplink()
 	if err != nil {
 		log.Printf("error encoding: %s\n", err.Error())
@@ -75,13 +438,109 @@ func generateUATEncodedTextReportMessage(msg *txwx.WeatherMessage) {
 	}
 }
 
+// generateUATEncodedNexradMessage merges a radar tile into the station's mosaic and sends the NEXRAD
+// blocks it covers.
+func generateUATEncodedNexradMessage(msg *txwx.WeatherMessage) {
+	t, origin, cellSize, err := decodeGridMessage(msg)
+	if err != nil {
+		log.Printf("radar: %s\n", err.Error())
+		return
+	}
+	key := fmt.Sprintf("%0.4f,%0.4f", msg.StationLat, msg.StationLng)
+	m, ok := radarMosaics[key]
+	if !ok {
+		m = &radarMosaic{Origin: origin, CellSize: cellSize, Cells: make(map[[2]int]byte)}
+		radarMosaics[key] = m
+	}
+	if !m.update(t, msg.ObservationTime) {
+		return
+	}
+	frames := nexradFrames(m, t)
+	if len(frames) == 0 {
+		return
+	}
+	var uatMsg uatsynth.UATMsg
+	uatMsg.Decoded = true
+	uatMsg.Lat = float64(msg.StationLat)
+	uatMsg.Lon = float64(msg.StationLng)
+	uatMsg.UTCCoupled = true
+	uatMsg.Frames = frames
+	outputUATMsg(&uatMsg)
+}
+
+// handleEmergency surfaces an emergency message. Unauthenticated ones are only logged. Returns the
+// receive log line.
+func handleEmergency(msg *txwx.WeatherMessage) string {
+	text := "EMERGENCY " + msg.Ident + " " + msg.TextData
+	if !verifyEmergencyMAC(globalSettings.EmergencyKey, msg.Ident, msg.TextData, msg.ValidFrom, msg.ValidTo, msg.Mac) {
+		return "unverified " + text
+	}
+	now := time.Now()
+	validTo := time.Unix(int64(msg.ValidTo), 0)
+	if now.After(validTo) {
+		return "expired " + text
+	}
+	key := msg.Ident + "|" + msg.TextData
+	emergenciesMutex.Lock()
+	e, ok := activeEmergencies[key]
+	if !ok {
+		e = &receivedEmergency{
+			Ident:      msg.Ident,
+			Text:       msg.TextData,
+			ValidFrom:  time.Unix(int64(msg.ValidFrom), 0),
+			ValidTo:    validTo,
+			StationLat: msg.StationLat,
+			StationLng: msg.StationLng,
+		}
+		activeEmergencies[key] = e
+		log.Printf("*** EMERGENCY from station (%0.4f, %0.4f) until %s: %s %s ***\n", msg.StationLat, msg.StationLng, validTo.UTC().Format(time.RFC3339), msg.Ident, msg.TextData)
+	}
+	output := now.Sub(e.LastOutput) >= EMERGENCY_OUTPUT_REPEAT
+	if output {
+		e.LastOutput = now
+	}
+	emergenciesMutex.Unlock()
+	if output {
+		generateUATEncodedTextReportMessage(msg)
+	}
+	return text
+}
+
+// handleEmergencyAPI reports the emergencies in effect, e.g. {"Emergency": true, "Notices": [...]}.
+func handleEmergencyAPI(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	notices := make([]receivedEmergency, 0)
+	emergenciesMutex.Lock()
+	for k, e := range activeEmergencies {
+		if now.After(e.ValidTo) {
+			delete(activeEmergencies, k)
+			continue
+		}
+		notices = append(notices, *e)
+	}
+	emergenciesMutex.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Emergency bool
+		Notices   []receivedEmergency
+	}{len(notices) > 0, notices})
+}
+
+func runAPI() {
+	http.HandleFunc("/emergency", handleEmergencyAPI)
+	err := http.ListenAndServe(apiAddr, nil)
+	if err != nil {
+		log.Printf("status API: %s\n", err.Error())
+	}
+}
+
 func printStats() {
 	statTimer := time.NewTicker(1 * time.Minute)
 	startTime := time.Now()
 	for {
 		<-statTimer.C
 		log.Printf("stats [started: %s]\n", humanize.RelTime(startTime, time.Now(), "ago", "from now"))
-		log.Printf(" - Messages received: %d, CRC errors: %d.\n", globalStatus.MessagesReceived, globalStatus.CRCErrors)
+		log.Printf(" - Messages received: %d, CRC errors: %d, out of TDMA slot: %d.\n", globalStatus.MessagesReceived, globalStatus.CRCErrors, globalStatus.OutOfSlot)
 		log.Printf(" - Current location: (%0.4f, %0.4f).\n", Location.GPSLatitude, Location.GPSLongitude)
 	}
 }
@@ -90,6 +549,7 @@ func startup() {
 	flag.Float64Var(&globalSettings.ManualLat, "lat", 0.0, "Station latitude. If entered with longitude, GPS data is not used.")
 	flag.Float64Var(&globalSettings.ManualLng, "lng", 0.0, "Station longitude. If entered with latitude, GPS data is not used.")
 
+	flag.StringVar(&apiAddr, "api", "127.0.0.1:9110", "Listen address of the status API (GET /emergency). Empty to disable.")
 	flag.IntVar(&productID, "productID", 0x7028, "RX dongle Product ID.")
 	flag.Parse()
 }
@@ -130,12 +590,20 @@ func main() {
 
 	go printStats()
 
+	emergenciesMutex = &sync.Mutex{}
+	if len(apiAddr) > 0 {
+		go runAPI()
+	}
+
 	u, err := uatradio.NewUATRadio(globalSettings.Freq, globalSettings.RadioModMode, productID)
 	if err != nil {
 		log.Printf("Unable to open radio: %s\n", err.Error())
 		panic(err)
 	}
 
+	follower := newHopFollower(u)
+	go follower.run()
+
 	if globalSettings.ManualLat != 0. || globalSettings.ManualLng != 0. {
 		// Save the manually entered lat/lng as the current location, for now. If a GPS lock is obtained,
 		//  then this position will be overwritten.
@@ -199,13 +667,84 @@ func main() {
 			continue
 		}
 
+		checkTDMASlot(msg)
+
 		switch msg.Type {
 		case txwx.WeatherMessage_METAR, txwx.WeatherMessage_TAF:
 			generateUATEncodedTextReportMessage(msg)
-			writeReceiveLog(msg.StationLat, msg.StationLng, msg.TextData)
+			if msg.Uncertified {
+				writeReceiveLog(msg.StationLat, msg.StationLng, "uncertified "+msg.TextData)
+			} else {
+				writeReceiveLog(msg.StationLat, msg.StationLng, msg.TextData)
+			}
+		case txwx.WeatherMessage_AIRMET, txwx.WeatherMessage_SIGMET, txwx.WeatherMessage_GAIRMET:
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, advisoryText(msg))
+		case txwx.WeatherMessage_WINDS_ALOFT:
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, strings.Replace(windsAloftText(msg), "\n", " ", -1))
+		case txwx.WeatherMessage_NOTAM:
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, notamText(msg))
+		case txwx.WeatherMessage_TFR:
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, tfrText(msg))
+		case txwx.WeatherMessage_BULLETIN:
+			if !bulletinInScope(msg) {
+				writeReceiveLog(msg.StationLat, msg.StationLng, "outofscope BULLETIN "+msg.Ident)
+				break
+			}
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, "BULLETIN "+msg.Ident+" "+msg.TextData)
+		case txwx.WeatherMessage_DERIVED:
+			if msg.Derived == nil {
+				break
+			}
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, derivedText(msg))
+		case txwx.WeatherMessage_EMERGENCY:
+			writeReceiveLog(msg.StationLat, msg.StationLng, handleEmergency(msg))
+		case txwx.WeatherMessage_ATIS:
+			generateUATEncodedTextReportMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, "ATIS "+msg.Ident+" "+msg.TextData)
+		case txwx.WeatherMessage_LIGHTNING:
+			if msg.Grid == nil {
+				break
+			}
+			text, strikes := lightningText(msg)
+			if strikes > 0 {
+				generateUATEncodedTextReportMessage(msg) // FIS-B has no strike product, Stratux gets the text.
+				log.Printf("Lightning from station (%0.4f, %0.4f): %s.\n", msg.StationLat, msg.StationLng, text)
+			}
+			writeReceiveLog(msg.StationLat, msg.StationLng, text)
+		case txwx.WeatherMessage_RADAR:
+			if msg.Grid == nil {
+				break
+			}
+			generateUATEncodedNexradMessage(msg)
+			writeReceiveLog(msg.StationLat, msg.StationLng, fmt.Sprintf("RADAR %dx%d%+d%+d", msg.Grid.Cols, msg.Grid.Rows, msg.Grid.ColOffset, msg.Grid.RowOffset))
 		case txwx.WeatherMessage_BEACON:
 			if msg.ServerStatus != nil {
-				beaconStr := fmt.Sprintf("TimeOk=%t, WeatherUpdatesOk=%t, MetarsTracked=%d, TafsTracked=%d", msg.ServerStatus.TimeOk, msg.ServerStatus.WeatherUpdatesOk, msg.ServerStatus.MetarsTracked, msg.ServerStatus.TafsTracked)
+				beaconStr := fmt.Sprintf("TimeOk=%t, WeatherUpdatesOk=%t, MetarsTracked=%d, TafsTracked=%d, AdvisoriesTracked=%d", msg.ServerStatus.TimeOk, msg.ServerStatus.WeatherUpdatesOk, msg.ServerStatus.MetarsTracked, msg.ServerStatus.TafsTracked, msg.ServerStatus.AdvisoriesTracked)
+				key := stationKey(msg)
+				if msg.ServerStatus.TdmaFrame > 0 && msg.ServerStatus.TdmaSlots > 0 {
+					stationSlots[key] = tdmaAssignment{Frame: int(msg.ServerStatus.TdmaFrame), Slots: int(msg.ServerStatus.TdmaSlots), Slot: int(msg.ServerStatus.TdmaSlot), Guard: int(msg.ServerStatus.TdmaGuard)}
+					beaconStr += fmt.Sprintf(", TDMA slot %d/%d of %dms", msg.ServerStatus.TdmaSlot, msg.ServerStatus.TdmaSlots, msg.ServerStatus.TdmaFrame)
+				} else {
+					delete(stationSlots, key)
+				}
+				follower.beacon(fmt.Sprintf("%s/%d", key, msg.ServerStatus.RadioIndex), msg.ServerStatus)
+				if len(msg.ServerStatus.FreqSchemeAirtime) > 0 {
+					bands := make([]string, 0, len(msg.ServerStatus.FreqSchemeAirtime))
+					for i, v := range msg.ServerStatus.FreqSchemeAirtime {
+						s := fmt.Sprintf("%dms", v)
+						if i < len(msg.ServerStatus.FreqSchemeDutyCycle) && msg.ServerStatus.FreqSchemeDutyCycle[i] > 0 {
+							s += fmt.Sprintf("/%0.2f%%", float64(msg.ServerStatus.FreqSchemeDutyCycle[i])/100)
+						}
+						bands = append(bands, s)
+					}
+					beaconStr += ", airtime by slot " + strings.Join(bands, " ")
+				}
 				log.Printf("Received beacon message from station (%0.4f, %0.4f): %s.\n", msg.StationLat, msg.StationLng, beaconStr)
 				writeReceiveLog(msg.StationLat, msg.StationLng, beaconStr)
 			}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"./proto"
+	"fmt"
+	"github.com/kellydunn/golang-geo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTuner records where the receiver was tuned last.
+type fakeTuner struct {
+	mu      sync.Mutex
+	freq    float64
+	modMode byte
+}
+
+func (r *fakeTuner) SetFrequency(freq float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.freq = freq
+	return nil
+}
+
+func (r *fakeTuner) SetModMode(modMode byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modMode = modMode
+	return nil
+}
+
+func (r *fakeTuner) tuned() (float64, byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.freq, r.modMode
+}
+
+func TestBeaconScheme(t *testing.T) {
+	resetSettings()
+	tests := []struct {
+		name   string
+		status *txwx.ServerStatus
+		want   []FreqSchemeEntry
+	}{
+		{"single slot", &txwx.ServerStatus{FreqBandStart: 902, FreqSchemeList: []uint32{13 * 65536}, FreqSchemeDwell: []uint32{10000}, FreqSchemeModmode: []uint32{1}},
+			[]FreqSchemeEntry{{Freq: 915.0, Dwell: 10000, ModMode: 1}}},
+		{"hopping", &txwx.ServerStatus{FreqBandStart: 863, FreqSchemeList: []uint32{6*65536 + 32768, 6*65536 + 49152}, FreqSchemeDwell: []uint32{400, 400}, FreqSchemeModmode: []uint32{0, 3}},
+			[]FreqSchemeEntry{{Freq: 869.5, Dwell: 400, ModMode: 0}, {Freq: 869.75, Dwell: 400, ModMode: 3}}},
+		{"no scheme", &txwx.ServerStatus{FreqBandStart: 902}, nil},
+	}
+	for _, tt := range tests {
+		got := beaconScheme(tt.status)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestHopFollower(t *testing.T) {
+	resetSettings()
+	c := newFakeClock(time.Unix(3000, 0)) // Start of the hop cycle.
+	defer useClock(c)()
+	r := &fakeTuner{freq: globalSettings.Freq, modMode: globalSettings.RadioModMode}
+	f := newHopFollower(r)
+	go f.run()
+	c.waitTimers(1)
+
+	hopping := &txwx.ServerStatus{FreqBandStart: 902, FreqSchemeList: []uint32{13 * 65536, 14 * 65536},
+		FreqSchemeDwell: []uint32{1000, 1000}, FreqSchemeModmode: []uint32{1, 3}}
+	f.beacon("44.2500,-81.6000/0", hopping)
+	c.waitTimers(2)
+	if freq, mode := r.tuned(); freq != 915.0 || mode != 1 {
+		t.Errorf("first slot: tuned to %0.3f MHz mode %d", freq, mode)
+	}
+	c.advance(time.Second)
+	if freq, mode := r.tuned(); freq != 916.0 || mode != 3 {
+		t.Errorf("second slot: tuned to %0.3f MHz mode %d", freq, mode)
+	}
+
+	// Another station's schedule doesn't take over.
+	f.beacon("45.0000,-80.0000/0", &txwx.ServerStatus{FreqBandStart: 902, FreqSchemeList: []uint32{10 * 65536}, FreqSchemeDwell: []uint32{10000}})
+	c.advance(time.Second)
+	if freq, _ := r.tuned(); freq != 915.0 {
+		t.Errorf("followed the other station to %0.3f MHz", freq)
+	}
+
+	// The station goes quiet, back to Freq.
+	c.advance(HOP_FOLLOW_TIMEOUT + time.Second)
+	if freq, mode := r.tuned(); freq != globalSettings.Freq || mode != globalSettings.RadioModMode {
+		t.Errorf("after timeout: tuned to %0.3f MHz mode %d", freq, mode)
+	}
+}
+
+func TestLightningText(t *testing.T) {
+	msg := &txwx.WeatherMessage{
+		Type:       txwx.WeatherMessage_LIGHTNING,
+		StationLat: 44.25,
+		StationLng: -81.60,
+		Grid:       &txwx.Grid{Cols: 2, Rows: 2, CellSize: 15000, ColOffset: -1, RowOffset: -1, Cells: encodeGridRLE([]byte{0, 3, 0, 1})},
+	}
+	origin := geo.NewPoint(float64(msg.StationLat), float64(msg.StationLng))
+	north, here := gridCellCenter(origin, 15, -1, 0), gridCellCenter(origin, 15, 0, 0)
+	want := fmt.Sprintf("LIGHTNING 15KM %0.4f,%0.4f:3 %0.4f,%0.4f:1", north.Lat(), north.Lng(), here.Lat(), here.Lng())
+	text, strikes := lightningText(msg)
+	if text != want || strikes != 4 {
+		t.Errorf("lightningText() = %q, %d, want %q, 4", text, strikes, want)
+	}
+	msg.Grid.Cells = []byte{0x80} // Says 9 cells.
+	if _, strikes := lightningText(msg); strikes != 0 {
+		t.Errorf("%d strikes in a bad grid", strikes)
+	}
+}
+
+func TestCheckTDMASlot(t *testing.T) {
+	resetSettings()
+	c := newFakeClock(time.Unix(8000, 0))
+	defer useClock(c)()
+	defer func() { stationSlots = make(map[string]tdmaAssignment) }()
+	stationSlots = make(map[string]tdmaAssignment)
+	globalStatus.OutOfSlot = 0
+
+	// Slot 1 of 4 in a 1s frame, 250-500ms, with a 20ms guard. The station moves between messages.
+	beacon := &txwx.WeatherMessage{Type: txwx.WeatherMessage_BEACON, StationId: 0x1234abcd, StationLat: 44.25, StationLng: -81.60}
+	stationSlots[stationKey(beacon)] = tdmaAssignment{Frame: 1000, Slots: 4, Slot: 1, Guard: 20}
+	msg := &txwx.WeatherMessage{Type: txwx.WeatherMessage_METAR, StationId: 0x1234abcd, StationLat: 44.31, StationLng: -81.52}
+	tests := []struct {
+		at  time.Duration // Into the frame, in order.
+		out bool
+	}{
+		{100 * time.Millisecond, true},
+		{300 * time.Millisecond, false},
+		{499 * time.Millisecond, false},
+		{510 * time.Millisecond, false}, // Decoded within the guard time after the slot.
+		{530 * time.Millisecond, true},
+	}
+	var at time.Duration
+	for _, tt := range tests {
+		c.advance(tt.at - at)
+		at = tt.at
+		before := globalStatus.OutOfSlot
+		checkTDMASlot(msg)
+		if out := globalStatus.OutOfSlot > before; out != tt.out {
+			t.Errorf("at %s: out of slot %t, want %t", tt.at, out, tt.out)
+		}
+	}
+
+	// Another station at the same position isn't held to the slot.
+	before := globalStatus.OutOfSlot
+	checkTDMASlot(&txwx.WeatherMessage{Type: txwx.WeatherMessage_METAR, StationId: 0x5678, StationLat: 44.25, StationLng: -81.60})
+	if globalStatus.OutOfSlot != before {
+		t.Errorf("message from another station counted out of slot")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"./proto"
+	"fmt"
+	"github.com/cyoung/ADDS"
+	"github.com/kellydunn/golang-geo"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scheduledReport is one report competing for airtime in the carousel.
+type scheduledReport struct {
+	Key      string    // Product and station, e.g. "METAR KDEN".
+	Version  string    // Changes whenever the report does, see reportVersion().
+	Weight   float64   // Share of passes the report goes out in, 1 = every pass.
+	Observed time.Time // Observation (METAR) or bulletin (TAF) time, for MaxAge and age weighting.
+	New      bool      // Set by plan() while the report is in its burst.
+	Packet   *cachedPacket
+}
+
+// carousel spreads reports over passes by weight. Each pass a report earns its weight in credit and
+// goes out once it has a full credit, so a 0.25 report goes out every fourth pass.
+//
+// New and changed reports first go out in every one of BurstCount passes (or the product's Repetitions).
+// After that they drop to their weight times MaintenanceWeight, and no more often than the product's
+// Cadence.
+type carousel struct {
+	credit   map[string]float64
+	versions map[string]string
+	burst    map[string]int // Burst passes left.
+	lastSent map[string]time.Time
+}
+
+func newCarousel() *carousel {
+	return &carousel{
+		credit:   make(map[string]float64),
+		versions: make(map[string]string),
+		burst:    make(map[string]int),
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// reportVersion identifies one issue of a station's report, e.g. "KDEN 1571514780 9c2f41d0".
+func reportVersion(station string, observationTime time.Time, text string) string {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	return fmt.Sprintf("%s %d %08x", station, observationTime.Unix(), h.Sum32())
+}
+
+// distanceFactor halves the weight at DistanceScale statute miles from the station.
+func distanceFactor(lat, lng float64) float64 {
+	cfg := globalSettings.Scheduler
+	if stationGeoPt == nil || cfg.DistanceScale <= 0 {
+		return 1
+	}
+	d := stationGeoPt.GreatCircleDistance(geo.NewPoint(lat, lng)) / KM_PER_SM
+	return 1 / (1 + d/cfg.DistanceScale)
+}
+
+// clampWeight keeps a weight between MinWeight and 1.
+func clampWeight(w float64) float64 {
+	if w > 1 {
+		return 1
+	}
+	if w < globalSettings.Scheduler.MinWeight {
+		return globalSettings.Scheduler.MinWeight
+	}
+	return w
+}
+
+// metarWeight favors close stations and worse flight categories. It doesn't change between passes,
+// agedWeight then favors fresh observations.
+func metarWeight(m ADDS.ADDSMETAR) float64 {
+	cfg := globalSettings.Scheduler
+	w := cfg.METARWeight * distanceFactor(m.Latitude, m.Longitude)
+	if f, ok := cfg.CategoryWeights[flightCategory(parseMETARText(m.Text)).String()]; ok {
+		w *= f
+	}
+	return w
+}
+
+// agedWeight scales down the weight of a report observed at observed as it ages.
+func agedWeight(w float64, observed time.Time, now time.Time) float64 {
+	if cfg := globalSettings.Scheduler; cfg.AgeScale > 0 {
+		age := now.Sub(observed).Minutes()
+		if age > 0 {
+			w /= 1 + age/cfg.AgeScale
+		}
+	}
+	return clampWeight(w)
+}
+
+func tafWeight(t ADDS.ADDSTAF) float64 {
+	return clampWeight(globalSettings.Scheduler.TAFWeight * distanceFactor(t.Latitude, t.Longitude))
+}
+
+// reportSet is one weather generation of METARs and TAFs, and the products derived from the METARs,
+// ready for the carousel. Versions, packets and weights are worked out when the data changes rather
+// than on every pass.
+type reportSet struct {
+	generation uint64
+	lat, lng   float32           // Station position the packets carry.
+	reports    []scheduledReport // METARs and TAFs.
+	derived    []scheduledReport
+}
+
+// newReportSet builds the reports of the types carries is true for. Reports unchanged since the last
+// set come from cache, and those gone from the data are dropped from it.
+func newReportSet(generation uint64, metars []ADDS.ADDSMETAR, tafs []ADDS.ADDSTAF, carries func(txwx.WeatherMessage_Type) bool, cache *packetCache) *reportSet {
+	s := &reportSet{generation: generation, lat: Location.GPSLatitude, lng: Location.GPSLongitude}
+	for _, v := range metars {
+		version := reportVersion(v.StationID, v.Observation.Time, v.Text)
+		if carries(txwx.WeatherMessage_METAR) {
+			key := "METAR " + v.StationID
+			s.reports = append(s.reports, scheduledReport{
+				Key:      key,
+				Version:  version,
+				Weight:   metarWeight(v),
+				Observed: v.Observation.Time,
+				Packet:   cache.lookup(key, version, func() *txwx.WeatherMessage { return createMETARWeatherMessage(v) }),
+			})
+		}
+		if carries(txwx.WeatherMessage_DERIVED) {
+			key := "DERIVED " + v.StationID
+			s.derived = append(s.derived, scheduledReport{
+				Key:      key,
+				Version:  version,
+				Observed: v.Observation.Time,
+				Packet:   cache.lookup(key, version, func() *txwx.WeatherMessage { return createDerivedWeatherMessage(v) }),
+			})
+		}
+	}
+	if carries(txwx.WeatherMessage_TAF) {
+		for _, v := range tafs {
+			if strings.HasPrefix(v.Text, "TAF") {
+				v.Text = strings.TrimSpace(v.Text[3:]) // RX puts it back.
+			}
+			key := "TAF " + v.StationID
+			version := reportVersion(v.StationID, v.BulletinTime.Time, v.Text)
+			s.reports = append(s.reports, scheduledReport{
+				Key:      key,
+				Version:  version,
+				Weight:   tafWeight(v),
+				Observed: v.BulletinTime.Time,
+				Packet:   cache.lookup(key, version, func() *txwx.WeatherMessage { return createTAFWeatherMessage(v) }),
+			})
+		}
+	}
+	cache.sweep()
+	return s
+}
+
+// stale checks if the set is out of date for generation, or the station has moved since it was built.
+func (s *reportSet) stale(generation uint64) bool {
+	return s == nil || s.generation != generation || s.lat != Location.GPSLatitude || s.lng != Location.GPSLongitude
+}
+
+// current returns the METARs and TAFs within their MaxAge at now, METAR weights aged.
+func (s *reportSet) current(now time.Time) []scheduledReport {
+	var ret []scheduledReport
+	for _, r := range s.reports {
+		t := r.Packet.Msg.Type
+		if tooOld(t, r.Observed, now) {
+			continue
+		}
+		if t == txwx.WeatherMessage_METAR {
+			r.Weight = agedWeight(r.Weight, r.Observed, now)
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+// plan returns the reports due in this pass, most important first.
+func (c *carousel) plan(reports []scheduledReport, now time.Time) []scheduledReport {
+	var due []scheduledReport
+	seen := make(map[string]bool)
+	for _, r := range reports {
+		t := r.Packet.Msg.Type
+		seen[r.Key] = true
+		if c.versions[r.Key] != r.Version {
+			c.versions[r.Key] = r.Version
+			c.burst[r.Key] = repetitions(t)
+		}
+		if c.burst[r.Key] > 0 {
+			c.burst[r.Key]--
+			c.lastSent[r.Key] = now
+			r.New = true
+			due = append(due, r)
+			continue
+		}
+		if now.Sub(c.lastSent[r.Key]) < sendInterval(t, 0) {
+			continue
+		}
+		r.Weight = clampWeight(r.Weight * globalSettings.Scheduler.MaintenanceWeight)
+		c.credit[r.Key] += r.Weight
+		if c.credit[r.Key] >= 1 {
+			c.credit[r.Key]--
+			c.lastSent[r.Key] = now
+			due = append(due, r)
+		}
+	}
+	// Forget reports that have dropped out of the data.
+	for k := range c.credit {
+		if !seen[k] {
+			delete(c.credit, k)
+		}
+	}
+	for k := range c.versions {
+		if !seen[k] {
+			delete(c.versions, k)
+			delete(c.burst, k)
+			delete(c.lastSent, k)
+		}
+	}
+	// New content first, then by weight.
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].New != due[j].New {
+			return due[i].New
+		}
+		return due[i].Weight > due[j].Weight
+	})
+	return due
+}
+
+// logCarousel prints the planned pass, for tuning the weights.
+func logCarousel(due []scheduledReport, total int) {
+	log.Printf("carousel: %d of %d reports due this pass.\n", len(due), total)
+	for _, r := range due {
+		if r.New {
+			log.Printf(" - %s new\n", r.Key)
+		} else {
+			log.Printf(" - %s %0.2f\n", r.Key, r.Weight)
+		}
+	}
+}
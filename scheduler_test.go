@@ -0,0 +1,206 @@
+package main
+
+import (
+	"./proto"
+	"github.com/cyoung/ADDS"
+	"hash/crc64"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testReport(key, version string, weight float64) scheduledReport {
+	return scheduledReport{
+		Key:     key,
+		Version: version,
+		Weight:  weight,
+		Packet:  &cachedPacket{Msg: &txwx.WeatherMessage{Type: txwx.WeatherMessage_METAR}},
+	}
+}
+
+// passes plans n passes interval apart and returns how many times each key went out.
+func passes(c *carousel, reports []scheduledReport, start time.Time, interval time.Duration, n int) map[string]int {
+	sent := make(map[string]int)
+	for i := 0; i < n; i++ {
+		for _, r := range c.plan(reports, start.Add(time.Duration(i)*interval)) {
+			sent[r.Key]++
+		}
+	}
+	return sent
+}
+
+func TestCarouselBurst(t *testing.T) {
+	resetSettings()
+	c := newCarousel()
+	start := time.Unix(1000, 0)
+	reports := []scheduledReport{testReport("METAR KDEN", "1", 0.25)}
+	for i := 0; i < globalSettings.Scheduler.BurstCount; i++ {
+		due := c.plan(reports, start)
+		if len(due) != 1 || !due[0].New {
+			t.Fatalf("pass %d: new report not in its burst", i)
+		}
+	}
+	// 0.25 * MaintenanceWeight 0.5 = one pass in eight.
+	if n := passes(c, reports, start, time.Second, 16)["METAR KDEN"]; n != 2 {
+		t.Errorf("sent %d times in 16 passes, want 2", n)
+	}
+	// A new version bursts again.
+	reports[0].Version = "2"
+	if due := c.plan(reports, start); len(due) != 1 || !due[0].New {
+		t.Errorf("changed report not in a burst")
+	}
+}
+
+func TestCarouselOrder(t *testing.T) {
+	resetSettings()
+	c := newCarousel()
+	start := time.Unix(1000, 0)
+	old := []scheduledReport{testReport("METAR KAPA", "1", 1), testReport("METAR KBJC", "1", 1)}
+	passes(c, old, start, time.Second, globalSettings.Scheduler.BurstCount)
+	reports := append(old, testReport("METAR KDEN", "1", 0.5))
+	due := c.plan(reports, start)
+	if len(due) == 0 || due[0].Key != "METAR KDEN" {
+		t.Errorf("new report not first: %v", due)
+	}
+}
+
+func TestCarouselForgets(t *testing.T) {
+	resetSettings()
+	c := newCarousel()
+	c.plan([]scheduledReport{testReport("METAR KDEN", "1", 1)}, time.Unix(1000, 0))
+	c.plan(nil, time.Unix(1001, 0))
+	if len(c.credit)+len(c.versions)+len(c.burst)+len(c.lastSent) != 0 {
+		t.Errorf("state left for a dropped report")
+	}
+}
+
+func TestCarouselCadence(t *testing.T) {
+	resetSettings()
+	globalSettings.Scheduler.MaintenanceWeight = 1
+	globalSettings.Cadence = map[string]ProductCadence{"METAR": {Repetitions: 2, Cadence: 60}}
+	c := newCarousel()
+	reports := []scheduledReport{testReport("METAR KDEN", "1", 1)}
+	var got []int
+	for i := 0; i < 10; i++ {
+		got = append(got, len(c.plan(reports, time.Unix(1000+int64(i)*20, 0))))
+	}
+	// Two repetitions, then every 60 seconds after the last one.
+	want := []int{1, 1, 0, 0, 1, 0, 0, 1, 0, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("passes = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestClampWeight(t *testing.T) {
+	resetSettings()
+	tests := []struct {
+		in, want float64
+	}{
+		{2, 1},
+		{1, 1},
+		{0.5, 0.5},
+		{0.01, globalSettings.Scheduler.MinWeight},
+	}
+	for _, tt := range tests {
+		if got := clampWeight(tt.in); got != tt.want {
+			t.Errorf("clampWeight(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReportVersion(t *testing.T) {
+	at := time.Unix(1571514780, 0)
+	a := reportVersion("KDEN", at, "KDEN 191953Z 27010KT")
+	if a != reportVersion("KDEN", at, "KDEN 191953Z 27010KT") {
+		t.Errorf("version not stable")
+	}
+	if a == reportVersion("KDEN", at, "KDEN 191953Z 27011KT") {
+		t.Errorf("text change didn't change the version")
+	}
+	if a == reportVersion("KDEN", at.Add(time.Hour), "KDEN 191953Z 27010KT") {
+		t.Errorf("observation time change didn't change the version")
+	}
+}
+
+func TestReportSet(t *testing.T) {
+	resetSettings()
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	globalSettings.Scheduler.AgeScale = 60
+	globalSettings.Cadence = map[string]ProductCadence{"METAR": {MaxAge: 90}}
+	old := Location
+	defer func() { Location = old }()
+	Location.GPSLatitude, Location.GPSLongitude = 39.8561, -104.6737
+	now := time.Unix(1571514780, 0)
+	metar := func(id, text string, age time.Duration) ADDS.ADDSMETAR {
+		var m ADDS.ADDSMETAR
+		m.StationID = id
+		m.Text = id + " " + text
+		m.Observation.Time = now.Add(-age)
+		return m
+	}
+	metars := []ADDS.ADDSMETAR{
+		metar("KDEN", "191953Z 27010KT 10SM FEW080 24/06 A3002", 0),
+		metar("KBJC", "191950Z 29012KT 10SM SKC 22/04 A3004", 60*time.Minute),
+		metar("KAPA", "191853Z 18005KT 10SM SKC 21/05 A3003", 120*time.Minute), // Past MaxAge.
+	}
+	var taf ADDS.ADDSTAF
+	taf.StationID = "KDEN"
+	taf.Text = "TAF KDEN 191720Z 1918/2024 27010KT P6SM FEW080"
+	taf.BulletinTime.Time = now.Add(-3 * time.Hour)
+	tafs := []ADDS.ADDSTAF{taf}
+	all := func(txwx.WeatherMessage_Type) bool { return true }
+	cache := newPacketCache()
+
+	misses := atomic.LoadUint64(&cacheStats.Misses)
+	s := newReportSet(1, metars, tafs, all, cache)
+	if len(s.reports) != 4 || len(s.derived) != 3 {
+		t.Fatalf("%d reports, %d derived", len(s.reports), len(s.derived))
+	}
+	if n := atomic.LoadUint64(&cacheStats.Misses) - misses; n != 7 {
+		t.Errorf("%d reports encoded, want 7", n)
+	}
+	if s.stale(1) || !s.stale(2) {
+		t.Errorf("stale() wrong for the generation")
+	}
+	if got := s.reports[3].Packet.Msg.TextData; got != "KDEN 191720Z 1918/2024 27010KT P6SM FEW080" {
+		t.Errorf("TAF sent as %q", got)
+	}
+
+	current := s.current(now)
+	if len(current) != 3 {
+		t.Fatalf("%d current reports, want KAPA dropped", len(current))
+	}
+	base := clampWeight(metarWeight(metars[1]))
+	if got, want := current[1].Weight, clampWeight(base/2); got != want {
+		t.Errorf("hour old METAR weighs %g, want %g", got, want)
+	}
+	if current[0].Weight != clampWeight(metarWeight(metars[0])) || s.reports[1].Weight != metarWeight(metars[1]) {
+		t.Errorf("weights aged in the set, or a fresh report aged")
+	}
+
+	// Next generation, one METAR changed and one gone.
+	metars[0] = metar("KDEN", "192053Z 27012KT 10SM FEW080 24/06 A3001", 0)
+	misses = atomic.LoadUint64(&cacheStats.Misses)
+	s = newReportSet(2, metars[:2], tafs, all, cache)
+	if n := atomic.LoadUint64(&cacheStats.Misses) - misses; n != 2 {
+		t.Errorf("%d reports encoded, want the changed METAR and its derived product", n)
+	}
+	if _, ok := cache.entries["METAR KAPA"]; ok {
+		t.Errorf("METAR gone from the data still cached")
+	}
+
+	Location.GPSLatitude += 0.5
+	if !s.stale(2) {
+		t.Errorf("not stale after the station moved")
+	}
+	misses = atomic.LoadUint64(&cacheStats.Misses)
+	s = newReportSet(2, metars[:2], tafs, all, cache)
+	if n := atomic.LoadUint64(&cacheStats.Misses) - misses; n != 5 {
+		t.Errorf("%d reports encoded after the station moved, want all 5", n)
+	}
+	if s.reports[0].Packet.Msg.StationLat != Location.GPSLatitude {
+		t.Errorf("packet carries the old station position")
+	}
+}
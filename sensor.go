@@ -0,0 +1,184 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	SENSOR_MAX_AGE     = 10 * time.Minute // Don't broadcast observations older than this.
+	METERS_PER_FOOT    = 0.3048
+	KT_PER_MPS         = 1.943844
+	MB_PER_INHG        = 33.8639
+	SENSOR_REMARK      = "RMK NOT CERTIFIED"
+	WEATHERFLOW_OBS_ST = "obs_st"
+)
+
+// sensorObservation is the latest reading from the local weather station.
+type sensorObservation struct {
+	Time        time.Time
+	WindDir     float64 // Degrees true.
+	WindSpeed   float64 // Knots.
+	WindGust    float64 // Knots.
+	TempC       float64
+	DewpointC   float64
+	HasDewpoint bool
+	PressureMb  float64 // Station pressure. 0 = unknown.
+}
+
+// sensorLine is the generic line protocol, one JSON object per line:
+//
+//	{"time": 1571500000, "wind_dir": 270, "wind_kt": 10, "gust_kt": 18, "temp_c": 15.2, "rh": 61, "pressure_mb": 978.4}
+//
+// WeatherFlow "obs_st" broadcasts are accepted as well.
+type sensorLine struct {
+	Type       string      `json:"type"`
+	Obs        [][]float64 `json:"obs"`
+	Time       int64       `json:"time"`
+	WindDir    float64     `json:"wind_dir"`
+	WindKt     float64     `json:"wind_kt"`
+	GustKt     float64     `json:"gust_kt"`
+	TempC      *float64    `json:"temp_c"`
+	DewpointC  *float64    `json:"dewpoint_c"`
+	RH         *float64    `json:"rh"`
+	PressureMb float64     `json:"pressure_mb"`
+}
+
+var latestSensorObs *sensorObservation // Protected by lookupMutex.
+
+// dewpointFromRH uses the Magnus approximation.
+func dewpointFromRH(tempC, rh float64) float64 {
+	const b, c = 17.62, 243.12
+	gamma := math.Log(rh/100.0) + b*tempC/(c+tempC)
+	return c * gamma / (b - gamma)
+}
+
+// altimeterSetting converts station pressure to an altimeter setting in inHg (NWS formula).
+func altimeterSetting(stationPressureMb, elevationFt float64) float64 {
+	const n = 0.190284
+	h := elevationFt * METERS_PER_FOOT
+	p := stationPressureMb - 0.3
+	alt := p * math.Pow(1+(math.Pow(1013.25, n)*0.0065/288.0)*(h/math.Pow(p, n)), 1/n)
+	return alt / MB_PER_INHG
+}
+
+func parseSensorLine(data []byte) (*sensorObservation, error) {
+	var l sensorLine
+	err := json.Unmarshal(data, &l)
+	if err != nil {
+		return nil, err
+	}
+	if l.Type == WEATHERFLOW_OBS_ST {
+		// [epoch, lull m/s, avg m/s, gust m/s, direction, interval, pressure mb, temp C, RH %, ...]
+		if len(l.Obs) == 0 || len(l.Obs[0]) < 9 {
+			return nil, errors.New("parseSensorLine(): short obs_st.")
+		}
+		o := l.Obs[0]
+		return &sensorObservation{
+			Time:        time.Unix(int64(o[0]), 0),
+			WindSpeed:   o[2] * KT_PER_MPS,
+			WindGust:    o[3] * KT_PER_MPS,
+			WindDir:     o[4],
+			PressureMb:  o[6],
+			TempC:       o[7],
+			DewpointC:   dewpointFromRH(o[7], o[8]),
+			HasDewpoint: o[8] > 0,
+		}, nil
+	}
+	if len(l.Type) > 0 {
+		return nil, nil // Other WeatherFlow messages (rapid_wind, hub_status, ...).
+	}
+	if l.TempC == nil {
+		return nil, errors.New("parseSensorLine(): no temperature.")
+	}
+	obs := &sensorObservation{
+		Time:       time.Unix(l.Time, 0),
+		WindDir:    l.WindDir,
+		WindSpeed:  l.WindKt,
+		WindGust:   l.GustKt,
+		TempC:      *l.TempC,
+		PressureMb: l.PressureMb,
+	}
+	if l.Time == 0 {
+		obs.Time = sysClock.Now()
+	}
+	if l.DewpointC != nil {
+		obs.DewpointC = *l.DewpointC
+		obs.HasDewpoint = true
+	} else if l.RH != nil && *l.RH > 0 {
+		obs.DewpointC = dewpointFromRH(obs.TempC, *l.RH)
+		obs.HasDewpoint = true
+	}
+	return obs, nil
+}
+
+func formatMETARTemp(t float64) string {
+	r := int(math.Floor(t + 0.5))
+	if r < 0 {
+		return fmt.Sprintf("M%02d", -r)
+	}
+	return fmt.Sprintf("%02d", r)
+}
+
+// sensorMETARText synthesizes an AUTO METAR from a sensor observation, e.g.
+// "K1A2 191955Z AUTO 27010G18KT 15/08 A2992 RMK NOT CERTIFIED". Visibility and sky condition are
+// not measured and left out.
+func sensorMETARText(ident string, elevationFt float64, obs *sensorObservation) string {
+	words := []string{ident, obs.Time.UTC().Format("021504Z"), "AUTO"}
+	speed := int(math.Floor(obs.WindSpeed + 0.5))
+	gust := int(math.Floor(obs.WindGust + 0.5))
+	dir := int(math.Floor(obs.WindDir/10.0+0.5)) * 10 % 360
+	if dir == 0 {
+		dir = 360
+	}
+	switch {
+	case speed == 0:
+		words = append(words, "00000KT")
+	case gust-speed >= 10:
+		words = append(words, fmt.Sprintf("%03d%02dG%02dKT", dir, speed, gust))
+	default:
+		words = append(words, fmt.Sprintf("%03d%02dKT", dir, speed))
+	}
+	temps := formatMETARTemp(obs.TempC) + "/"
+	if obs.HasDewpoint {
+		temps += formatMETARTemp(obs.DewpointC)
+	}
+	words = append(words, temps)
+	if obs.PressureMb > 0 {
+		words = append(words, fmt.Sprintf("A%04d", int(math.Floor(altimeterSetting(obs.PressureMb, elevationFt)*100+0.5))))
+	}
+	words = append(words, SENSOR_REMARK)
+	return strings.Join(words, " ")
+}
+
+func setSensorObservation(data []byte) {
+	obs, err := parseSensorLine(data)
+	if err != nil {
+		log.Printf("sensor: %s\n", err.Error())
+		return
+	}
+	if obs == nil {
+		return
+	}
+	lookupMutex.Lock()
+	latestSensorObs = obs
+	lookupMutex.Unlock()
+}
+
+func createSensorWeatherMessage(obs *sensorObservation) *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_METAR,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		TextData:        sensorMETARText(globalSettings.SensorIdent, globalSettings.SensorElevation, obs),
+		ObservationTime: uint32(obs.Time.Unix()),
+		Uncertified:     true,
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseSensorLine(t *testing.T) {
+	c := newFakeClock(time.Unix(1571500600, 0))
+	defer useClock(c)()
+	tests := []struct {
+		name string
+		line string
+		err  bool
+		want *sensorObservation
+	}{
+		{"generic", `{"time": 1571500000, "wind_dir": 270, "wind_kt": 10, "gust_kt": 18, "temp_c": 15.2, "rh": 61, "pressure_mb": 978.4}`, false,
+			&sensorObservation{Time: time.Unix(1571500000, 0), WindDir: 270, WindSpeed: 10, WindGust: 18, TempC: 15.2, DewpointC: 7.72, HasDewpoint: true, PressureMb: 978.4}},
+		{"dewpoint over RH", `{"time": 1571500000, "temp_c": 10, "dewpoint_c": 3, "rh": 90}`, false,
+			&sensorObservation{Time: time.Unix(1571500000, 0), TempC: 10, DewpointC: 3, HasDewpoint: true}},
+		{"no time", `{"temp_c": 20, "rh": 50}`, false,
+			&sensorObservation{Time: time.Unix(1571500600, 0), TempC: 20, DewpointC: 9.26, HasDewpoint: true}},
+		{"RH 0", `{"time": 1571500000, "temp_c": 20, "rh": 0}`, false,
+			&sensorObservation{Time: time.Unix(1571500000, 0), TempC: 20}},
+		{"no temperature", `{"time": 1571500000, "wind_dir": 270, "wind_kt": 10}`, true, nil},
+		{"obs_st", `{"type": "obs_st", "obs": [[1571500000, 0.5, 2.0, 3.5, 180, 3, 843.0, -5, 80, 0, 0, 0, 0, 0, 0, 0, 2.4, 1]]}`, false,
+			&sensorObservation{Time: time.Unix(1571500000, 0), WindDir: 180, WindSpeed: 3.89, WindGust: 6.80, TempC: -5, DewpointC: -7.92, HasDewpoint: true, PressureMb: 843}},
+		{"obs_st RH 0", `{"type": "obs_st", "obs": [[1571500000, 0.5, 2.0, 3.5, 180, 3, 843.0, -5, 0]]}`, false,
+			&sensorObservation{Time: time.Unix(1571500000, 0), WindDir: 180, WindSpeed: 3.89, WindGust: 6.80, TempC: -5, PressureMb: 843}},
+		{"short obs_st", `{"type": "obs_st", "obs": [[1571500000, 0.5, 2.0]]}`, true, nil},
+		{"rapid_wind", `{"type": "rapid_wind", "ob": [1571500000, 2.3, 128]}`, false, nil},
+		{"not JSON", `270/10`, true, nil},
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	for _, tt := range tests {
+		obs, err := parseSensorLine([]byte(tt.line))
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error %v", tt.name, err)
+			continue
+		}
+		if tt.want == nil || obs == nil {
+			if obs != tt.want {
+				t.Errorf("%s: got %+v, want %+v", tt.name, obs, tt.want)
+			}
+			continue
+		}
+		w := tt.want
+		if !obs.Time.Equal(w.Time) || !near(obs.WindDir, w.WindDir) || !near(obs.WindSpeed, w.WindSpeed) || !near(obs.WindGust, w.WindGust) ||
+			!near(obs.TempC, w.TempC) || obs.HasDewpoint != w.HasDewpoint || (w.HasDewpoint && !near(obs.DewpointC, w.DewpointC)) || !near(obs.PressureMb, w.PressureMb) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, obs, w)
+		}
+	}
+}
+
+func TestDewpointFromRH(t *testing.T) {
+	tests := []struct {
+		temp, rh, want float64
+	}{
+		{15, 100, 15},
+		{20, 50, 9.26},
+		{15.2, 61, 7.72},
+		{-5, 80, -7.92},
+	}
+	for _, tt := range tests {
+		if got := dewpointFromRH(tt.temp, tt.rh); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("dewpointFromRH(%v, %v) = %0.2f, want %0.2f", tt.temp, tt.rh, got, tt.want)
+		}
+	}
+}
+
+func TestAltimeterSetting(t *testing.T) {
+	tests := []struct {
+		pressure, elevation, want float64
+	}{
+		{1013.25, 0, 29.91},
+		{978.4, 1000, 29.95},
+		{843.0, 5000, 29.91},
+	}
+	for _, tt := range tests {
+		if got := altimeterSetting(tt.pressure, tt.elevation); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("altimeterSetting(%v, %v) = %0.2f, want %0.2f", tt.pressure, tt.elevation, got, tt.want)
+		}
+	}
+}
+
+func TestSensorMETARText(t *testing.T) {
+	at := time.Date(2019, 10, 19, 19, 55, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		obs  sensorObservation
+		want string
+	}{
+		{"calm", sensorObservation{WindDir: 270, WindSpeed: 0.4, TempC: 15.2, DewpointC: 7.7, HasDewpoint: true},
+			"K1A2 191955Z AUTO 00000KT 15/08 RMK NOT CERTIFIED"},
+		{"gust", sensorObservation{WindDir: 268, WindSpeed: 10, WindGust: 20, TempC: 15, DewpointC: 8, HasDewpoint: true},
+			"K1A2 191955Z AUTO 27010G20KT 15/08 RMK NOT CERTIFIED"},
+		{"gust under the threshold", sensorObservation{WindDir: 268, WindSpeed: 10, WindGust: 19, TempC: 15, DewpointC: 8, HasDewpoint: true},
+			"K1A2 191955Z AUTO 27010KT 15/08 RMK NOT CERTIFIED"},
+		{"north", sensorObservation{WindDir: 2, WindSpeed: 10, TempC: 15, DewpointC: 8, HasDewpoint: true},
+			"K1A2 191955Z AUTO 36010KT 15/08 RMK NOT CERTIFIED"},
+		{"below freezing", sensorObservation{WindDir: 180, WindSpeed: 5, TempC: -5.4, DewpointC: -12.6, HasDewpoint: true},
+			"K1A2 191955Z AUTO 18005KT M05/M13 RMK NOT CERTIFIED"},
+		{"just below zero", sensorObservation{WindDir: 180, WindSpeed: 5, TempC: -0.4, DewpointC: -1.6, HasDewpoint: true},
+			"K1A2 191955Z AUTO 18005KT 00/M02 RMK NOT CERTIFIED"},
+		{"no dewpoint", sensorObservation{WindDir: 180, WindSpeed: 5, TempC: 15},
+			"K1A2 191955Z AUTO 18005KT 15/ RMK NOT CERTIFIED"},
+		{"altimeter", sensorObservation{WindDir: 180, WindSpeed: 5, TempC: 15, DewpointC: 8, HasDewpoint: true, PressureMb: 978.4},
+			"K1A2 191955Z AUTO 18005KT 15/08 A2995 RMK NOT CERTIFIED"},
+	}
+	for _, tt := range tests {
+		obs := tt.obs
+		obs.Time = at
+		if got := sensorMETARText("K1A2", 1000, &obs); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// TDMA: time is cut into GPS-aligned frames of TDMAFrame ms, each divided into TDMASlots equal slots.
+// A station only transmits in its own slot, less TDMAGuard ms at the end for clock error.
+
+func tdmaEnabled() bool {
+	return globalSettings.TDMAFrame > 0 && globalSettings.TDMASlots > 0
+}
+
+// validateTDMA checks that our slot leaves room after the guard time for the longest packet, which
+// takes longestPacket on the air, and that there is a StationID to derive the slot from if it isn't
+// configured. The slot must not depend on anything that changes, like the GPS position, or the
+// station would wander between slots.
+func validateTDMA(longestPacket time.Duration) error {
+	if !tdmaEnabled() {
+		return nil
+	}
+	if globalSettings.TDMASlot < 0 && len(globalSettings.StationID) == 0 {
+		return errors.New("TDMA needs a StationID to derive the slot from, or a TDMASlot")
+	}
+	if globalSettings.TDMAFrame/globalSettings.TDMASlots <= globalSettings.TDMAGuard {
+		return fmt.Errorf("TDMA slots of %dms are no longer than the %dms guard time", globalSettings.TDMAFrame/globalSettings.TDMASlots, globalSettings.TDMAGuard)
+	}
+	if tdmaSlotTime() < longestPacket {
+		return fmt.Errorf("TDMA slots leave %s after the guard time, the longest packet takes %s", tdmaSlotTime(), longestPacket)
+	}
+	return nil
+}
+
+// tdmaSlotTime is how long we may transmit in each slot, after the guard time.
+func tdmaSlotTime() time.Duration {
+	return time.Duration(globalSettings.TDMAFrame/globalSettings.TDMASlots-globalSettings.TDMAGuard) * time.Millisecond
+}
+
+// tdmaOwnSlot is the configured TDMASlot, or one derived from StationID when that is negative.
+func tdmaOwnSlot() int {
+	if globalSettings.TDMASlot >= 0 {
+		return globalSettings.TDMASlot % globalSettings.TDMASlots
+	}
+	return int(stationIDHash() % uint32(globalSettings.TDMASlots))
+}
+
+// stationIDHash identifies this station in its messages, so that receivers can tell it from others
+// wherever it is. 0 if there is no StationID.
+func stationIDHash() uint32 {
+	if len(globalSettings.StationID) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(globalSettings.StationID))
+	return h.Sum32()
+}
+
+// tdmaSlotAt is the slot in effect at t for the given frame length (ms) and slot count.
+func tdmaSlotAt(t time.Time, frame int, slots int) int {
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return int(ms%int64(frame)) * slots / frame
+}
+
+// tdmaWindow tells how much of our slot is left at t, less the guard time. If we're outside our slot,
+// open is false and wait is the time until it starts.
+func tdmaWindow(t time.Time) (open bool, remaining time.Duration, wait time.Duration) {
+	frame := int64(globalSettings.TDMAFrame)
+	slotLen := frame / int64(globalSettings.TDMASlots)
+	start := int64(tdmaOwnSlot()) * slotLen
+	end := start + slotLen - int64(globalSettings.TDMAGuard)
+	pos := (t.UnixNano() / int64(time.Millisecond)) % frame
+	if pos >= start && pos < end {
+		return true, time.Duration(end-pos) * time.Millisecond, 0
+	}
+	next := start - pos
+	if next <= 0 {
+		next += frame
+	}
+	return false, 0, time.Duration(next) * time.Millisecond
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateTDMA(t *testing.T) {
+	fast, slow := 50*time.Millisecond, 250*time.Millisecond // A full packet in mode 1 and 0.
+	tests := []struct {
+		frame, slots, slot, guard int
+		id                        string
+		longest                   time.Duration
+		ok                        bool
+	}{
+		{0, 4, -1, 20, "", slow, true}, // Off.
+		{1000, 4, -1, 20, "KDEN-TX1", fast, true},
+		{1000, 4, 2, 20, "", fast, true},
+		{1000, 4, -1, 20, "", fast, false}, // Nothing stable to derive the slot from.
+		{1000, 4, 2, 250, "", fast, false}, // No room after the guard time.
+		{1000, 4, 2, 20, "", slow, false},  // 230 ms left, a full packet in mode 0 takes 250 ms.
+		{1000, 2, 1, 20, "", slow, true},
+	}
+	for _, tt := range tests {
+		resetSettings()
+		globalSettings.TDMAFrame, globalSettings.TDMASlots, globalSettings.TDMASlot, globalSettings.TDMAGuard = tt.frame, tt.slots, tt.slot, tt.guard
+		globalSettings.StationID = tt.id
+		if err := validateTDMA(tt.longest); (err == nil) != tt.ok {
+			t.Errorf("%+v: validateTDMA() = %v", tt, err)
+		}
+	}
+}
+
+func TestTDMAOwnSlotStable(t *testing.T) {
+	resetSettings()
+	globalSettings.TDMAFrame, globalSettings.TDMASlots = 1000, 8
+	globalSettings.StationID = "KDEN-TX1"
+	slot := tdmaOwnSlot()
+	Location.GPSLatitude, Location.GPSLongitude = 39.8561, -104.6737
+	for i := 0; i < 10; i++ {
+		Location.GPSLatitude += 0.0001 // GPS noise.
+		if s := tdmaOwnSlot(); s != slot {
+			t.Fatalf("slot moved from %d to %d with the position", slot, s)
+		}
+	}
+	globalSettings.TDMASlot = 11
+	if s := tdmaOwnSlot(); s != 3 {
+		t.Errorf("TDMASlot 11 of 8 = %d, want 3", s)
+	}
+}
+
+func TestTDMAWindow(t *testing.T) {
+	resetSettings()
+	globalSettings.TDMAFrame, globalSettings.TDMASlots, globalSettings.TDMASlot, globalSettings.TDMAGuard = 1000, 4, 1, 20
+	frame := time.Unix(7000, 0)
+	tests := []struct {
+		at        time.Duration
+		open      bool
+		remaining time.Duration
+		wait      time.Duration
+	}{
+		{0, false, 0, 250 * time.Millisecond},
+		{250 * time.Millisecond, true, 230 * time.Millisecond, 0},
+		{479 * time.Millisecond, true, time.Millisecond, 0},
+		{480 * time.Millisecond, false, 0, 770 * time.Millisecond}, // Guard time.
+		{900 * time.Millisecond, false, 0, 350 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		open, remaining, wait := tdmaWindow(frame.Add(tt.at))
+		if open != tt.open || remaining != tt.remaining || wait != tt.wait {
+			t.Errorf("tdmaWindow(+%s) = %t, %s, %s", tt.at, open, remaining, wait)
+		}
+	}
+	if s := tdmaSlotAt(frame.Add(600*time.Millisecond), 1000, 4); s != 2 {
+		t.Errorf("tdmaSlotAt(+600ms) = %d, want 2", s)
+	}
+}
+
+func TestStationIDHash(t *testing.T) {
+	resetSettings()
+	if h := stationIDHash(); h != 0 {
+		t.Errorf("no StationID hashed to %08x", h)
+	}
+	globalSettings.StationID = "KDEN-TX1"
+	h := stationIDHash()
+	if h == 0 || h != stationIDHash() {
+		t.Errorf("StationID hashed to %08x, then %08x", h, stationIDHash())
+	}
+	globalSettings.StationID = "KDEN-TX2"
+	if stationIDHash() == h {
+		t.Errorf("two StationIDs hashed the same")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"./proto"
+	"encoding/json"
+	"github.com/kellydunn/golang-geo"
+	"io/ioutil"
+	"log"
+	"time"
+)
+
+// TFR is a temporary flight restriction from the operator's GeoJSON file. The area is either a polygon,
+// or a circle of RadiusNM around a single point.
+type TFR struct {
+	Ident    string
+	Text     string
+	Points   []*geo.Point
+	RadiusNM float64
+	Floor    int // Feet MSL.
+	Ceiling  int // Feet MSL.
+	Start    time.Time
+	End      time.Time
+}
+
+// tfrFeature is a GeoJSON feature, e.g.
+//
+//	{"type": "Feature",
+//	 "geometry": {"type": "Point", "coordinates": [-81.60, 44.25]},
+//	 "properties": {"ident": "9/1234", "text": "VIP MOVEMENT", "radius_nm": 10, "floor_ft": 0, "ceiling_ft": 17999,
+//	                "start": "2019-10-19T14:00:00Z", "end": "2019-10-19T20:00:00Z"}}
+type tfrFeature struct {
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Ident     string    `json:"ident"`
+		Text      string    `json:"text"`
+		RadiusNM  float64   `json:"radius_nm"`
+		FloorFt   int       `json:"floor_ft"`
+		CeilingFt int       `json:"ceiling_ft"`
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+	} `json:"properties"`
+}
+
+type tfrFeatureCollection struct {
+	Features []tfrFeature `json:"features"`
+}
+
+func (t TFR) Active(now time.Time) bool {
+	if now.Before(t.Start) {
+		return false
+	}
+	return t.End.IsZero() || now.Before(t.End)
+}
+
+// InRadiusOf checks if any part of the TFR is within radius statute miles of p.
+func (t TFR) InRadiusOf(radius float64, p *geo.Point) bool {
+	if p == nil || len(t.Points) == 0 {
+		return false
+	}
+	if t.RadiusNM > 0 {
+		return p.GreatCircleDistance(t.Points[0]) <= radius*KM_PER_SM+t.RadiusNM*KM_PER_NM
+	}
+	return polygonIntersectsCircle(t.Points, p, radius*KM_PER_SM)
+}
+
+// loadTFRs reads the operator's GeoJSON file. Malformed features, and TFRs whose text doesn't fit in one
+// packet with their area and times, are logged and skipped.
+func loadTFRs(fn string) ([]TFR, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	var fc tfrFeatureCollection
+	err = json.Unmarshal(buf, &fc)
+	if err != nil {
+		return nil, err
+	}
+	var ret []TFR
+	for _, f := range fc.Features {
+		t := TFR{
+			Ident:    f.Properties.Ident,
+			Text:     f.Properties.Text,
+			RadiusNM: f.Properties.RadiusNM,
+			Floor:    f.Properties.FloorFt,
+			Ceiling:  f.Properties.CeilingFt,
+			Start:    f.Properties.Start,
+			End:      f.Properties.End,
+		}
+		switch f.Geometry.Type {
+		case "Point":
+			var c []float64
+			err = json.Unmarshal(f.Geometry.Coordinates, &c)
+			if err != nil || len(c) < 2 {
+				log.Printf("TFR %s: bad point, skipping.\n", t.Ident)
+				continue
+			}
+			if t.RadiusNM <= 0 {
+				log.Printf("TFR %s: point without radius_nm, skipping.\n", t.Ident)
+				continue
+			}
+			t.Points = []*geo.Point{geo.NewPoint(c[1], c[0])}
+		case "Polygon":
+			var rings [][][]float64
+			err = json.Unmarshal(f.Geometry.Coordinates, &rings)
+			if err != nil || len(rings) == 0 || len(rings[0]) < 3 {
+				log.Printf("TFR %s: bad polygon, skipping.\n", t.Ident)
+				continue
+			}
+			// Outer ring only. GeoJSON repeats the first position at the end.
+			ring := rings[0]
+			if len(ring) > 3 && ring[0][0] == ring[len(ring)-1][0] && ring[0][1] == ring[len(ring)-1][1] {
+				ring = ring[:len(ring)-1]
+			}
+			bad := false
+			for _, c := range ring {
+				if len(c) < 2 {
+					bad = true
+					break
+				}
+				t.Points = append(t.Points, geo.NewPoint(c[1], c[0]))
+			}
+			if bad {
+				log.Printf("TFR %s: bad polygon, skipping.\n", t.Ident)
+				continue
+			}
+			t.RadiusNM = 0
+		default:
+			log.Printf("TFR %s: unsupported geometry %s, skipping.\n", t.Ident, f.Geometry.Type)
+			continue
+		}
+		if n := packetSize(createTFRWeatherMessage(t)); n >= MAX_PACKET_LEN {
+			log.Printf("TFR %s is too long (%d bytes, %d max), skipping.\n", t.Ident, n, MAX_PACKET_LEN-1)
+			continue
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
+
+func createTFRWeatherMessage(t TFR) *txwx.WeatherMessage {
+	msg := &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_TFR,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(sysClock.Now().Unix()),
+		Ident:           t.Ident,
+		TextData:        t.Text,
+		Area: &txwx.Area{
+			Polygon: encodePolygon(reducePolygon(t.Points, MAX_POLYGON_VERTICES)),
+			Floor:   uint32(t.Floor / 100),
+			Ceiling: uint32(t.Ceiling / 100),
+			Radius:  uint32(t.RadiusNM*10 + 0.5),
+		},
+	}
+	if !t.Start.IsZero() {
+		msg.ObservationTime = uint32(t.Start.Unix())
+		msg.ValidFrom = uint32(t.Start.Unix())
+	}
+	if !t.End.IsZero() {
+		msg.ValidTo = uint32(t.End.Unix())
+	}
+	return msg
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"github.com/kellydunn/golang-geo"
+	"hash/crc64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTFRsSkipsBadFeatures(t *testing.T) {
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	dir, err := ioutil.TempDir("", "txwx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var ring []string
+	for _, p := range circlePoints(geo.NewPoint(44.25, -81.60), 30, 40, true) {
+		ring = append(ring, fmt.Sprintf("[%f, %f]", p.Lng(), p.Lat()))
+	}
+	long := strings.Repeat("X", 120)
+	fn := filepath.Join(dir, "tfrs.json")
+	err = ioutil.WriteFile(fn, []byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-81.60, 44.25]},
+		 "properties": {"ident": "9/1234", "text": "VIP MOVEMENT", "radius_nm": 10, "ceiling_ft": 17999}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-81.60]},
+		 "properties": {"ident": "9/0001", "radius_nm": 10}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-81.60, 44.25]},
+		 "properties": {"ident": "9/0002"}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[-81.60, 44.25], [-81.50, 44.25]]},
+		 "properties": {"ident": "9/0003"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-81.60, 44.25]},
+		 "properties": {"ident": "9/0004", "text": "`+long+`", "radius_nm": 10}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[`+strings.Join(ring, ", ")+`]]},
+		 "properties": {"ident": "9/5678", "text": "FIRE FIGHTING", "ceiling_ft": 8000}}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfrs, err := loadTFRs(fn)
+	if err != nil {
+		t.Fatalf("loadTFRs: %s", err.Error())
+	}
+	if len(tfrs) != 2 || tfrs[0].Ident != "9/1234" || tfrs[1].Ident != "9/5678" {
+		t.Fatalf("loaded %+v", tfrs)
+	}
+	for _, tfr := range tfrs {
+		if n := len(preparePacketFromWeatherMessage(createTFRWeatherMessage(tfr))); n >= MAX_PACKET_LEN {
+			t.Errorf("TFR %s loaded, but its packet is %d bytes", tfr.Ident, n)
+		}
+	}
+}
@@ -6,25 +6,46 @@ import (
 	"encoding/binary"
 	"errors"
 	"flag"
+	"fmt"
 	"github.com/cyoung/ADDS"
 	"github.com/golang/protobuf/proto"
 	"github.com/kellydunn/golang-geo"
 	"hash/crc64"
 	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	uatradio "../gouatradio"
 	humanize "github.com/dustin/go-humanize"
 )
 
 const (
 	REPORTS_UPDATE_TIME = 5 * time.Minute
+	METAR_RADIUS        = 500 // Statute miles around the station to request METARs/TAFs for.
+	PACKET_HEADER_LEN   = 10  // Length and CRC in front of each message.
+	MAX_PACKET_LEN      = 150 // Bytes, header included. Longer packets are refused.
 	BEACON_TIME         = 1 * time.Second
+	WINDS_UPDATE_TIME   = 1 * time.Hour   // FB forecasts are issued four times a day.
+	LOCAL_RELOAD_TIME   = 1 * time.Minute // How often operator maintained files (NOTAMs, TFRs, bulletins) are re-read.
+	CAROUSEL_PAUSE      = 100 * time.Millisecond
 )
 
 type status struct {
-	MessagesSent uint64
+	MessagesSent      uint64 // Atomic, as are the following. Every radio has its own carousel.
+	MessagesPreempted uint64 // Carousel messages dropped for an emergency.
+	NewReportBytes    uint64 // Carousel airtime spent on new and changed reports.
+	RepeatReportBytes uint64 // Carousel airtime spent on repeats of unchanged reports.
+	EmergenciesSent   uint64
+}
+
+// metarSourceStatus counts what each METAR source contributed in the last update.
+type metarSourceStatus struct {
+	Fetched int // Reports returned by the source.
+	Added   int // Stations not already covered by an earlier source.
 }
 
 var txwxBuild string
@@ -37,17 +58,40 @@ var crc64Table *crc64.Table
 
 var lookupMutex *sync.Mutex // Protects the following weather data variables.
 var allMETARs []ADDS.ADDSMETAR
+var metarSourceStats map[string]metarSourceStatus // Keyed by source name, "radius" for the radius query.
 var allTAFs []ADDS.ADDSTAF
+var allAIRSIGMETs []AIRSIGMET
+var allGAIRMETs []GAIRMET
+var allWindsAloft []windsAloftStation
+var allNOTAMs []NOTAM
+var allTFRs []TFR
+var allBulletins []Bulletin
+var allRadarTiles []gridTile
+var radarTime time.Time // Modification time of the radar image the tiles were cut from.
+var allATISOverrides []ATISOverride
+var allEmergencies []Emergency
+var weatherGeneration uint64  // Bumped when an ADDS refresh brings new METARs or TAFs.
+var weatherFingerprint uint64 // Of the METARs and TAFs, see reportsFingerprint().
 
 // Run options.
-var beaconMode bool // Just send beacons, don't send any weather.
-var txMetars bool   // Send METARs on/off.
-var txTafs bool     // Send TAFs on/off.
+var beaconMode bool    // Just send beacons, don't send any weather.
+var txMetars bool      // Send METARs on/off.
+var txTafs bool        // Send TAFs on/off.
+var txSigmets bool     // Send AIRMETs/SIGMETs on/off.
+var txWinds bool       // Send winds aloft on/off.
+var txNotams bool      // Send NOTAMs from the local NOTAM file on/off.
+var txTfrs bool        // Send TFRs from the local TFR file on/off.
+var txBulletins bool   // Send operator bulletins on/off.
+var txRadar bool       // Send the local radar image on/off.
+var txDerived bool     // Send derived flight category and density altitude on/off.
+var txATIS bool        // Send runway in use for the configured airports on/off.
+var txGairmets bool    // Send G-AIRMETs on/off.
+var debugCarousel bool // Log the planned carousel every pass.
 
 func createMETARWeatherMessage(metar ADDS.ADDSMETAR) *txwx.WeatherMessage {
 	return &txwx.WeatherMessage{
 		Type:            txwx.WeatherMessage_METAR,
-		TxTime:          uint32(time.Now().Unix()),
+		TxTime:          uint32(sysClock.Now().Unix()),
 		StationLat:      Location.GPSLatitude,
 		StationLng:      Location.GPSLongitude,
 		TextData:        metar.Text,
@@ -58,7 +102,7 @@ func createMETARWeatherMessage(metar ADDS.ADDSMETAR) *txwx.WeatherMessage {
 func createTAFWeatherMessage(taf ADDS.ADDSTAF) *txwx.WeatherMessage {
 	return &txwx.WeatherMessage{
 		Type:            txwx.WeatherMessage_TAF,
-		TxTime:          uint32(time.Now().Unix()),
+		TxTime:          uint32(sysClock.Now().Unix()),
 		StationLat:      Location.GPSLatitude,
 		StationLng:      Location.GPSLongitude,
 		TextData:        taf.Text,
@@ -66,31 +110,166 @@ func createTAFWeatherMessage(taf ADDS.ADDSTAF) *txwx.WeatherMessage {
 	}
 }
 
-func sendBeaconMessage(u *uatradio.UATRadio) error {
+func createWindsAloftWeatherMessage(s windsAloftStation) *txwx.WeatherMessage {
+	return &txwx.WeatherMessage{
+		Type:            txwx.WeatherMessage_WINDS_ALOFT,
+		TxTime:          uint32(sysClock.Now().Unix()),
+		StationLat:      Location.GPSLatitude,
+		StationLng:      Location.GPSLongitude,
+		ObservationTime: uint32(s.ValidTime.Unix()),
+		ValidFrom:       uint32(s.UseFrom.Unix()),
+		ValidTo:         uint32(s.UseTo.Unix()),
+		Ident:           s.Ident,
+		Winds:           encodeWindsAloft(s.Levels),
+	}
+}
+
+// nearbyWindsAloft picks the FB stations within the coverage radius. FB bulletins carry no positions,
+// so stations are located through the METAR of the same airport ("DEN" -> "KDEN").
+func nearbyWindsAloft(stations []windsAloftStation, metars []ADDS.ADDSMETAR) []windsAloftStation {
+	if stationGeoPt == nil {
+		return nil
+	}
+	nearby := make(map[string]bool)
+	for _, m := range metars {
+		if len(m.StationID) != 4 {
+			continue
+		}
+		if stationGeoPt.GreatCircleDistance(geo.NewPoint(m.Latitude, m.Longitude)) <= globalSettings.CoverageRadius*KM_PER_SM {
+			nearby[m.StationID[1:]] = true
+		}
+	}
+	var ret []windsAloftStation
+	for _, s := range stations {
+		if nearby[s.Ident] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// metarSourceSelects checks if m falls within the source's region, or within METAR_RADIUS of the
+// station if the source has none.
+func metarSourceSelects(s METARSource, m ADDS.ADDSMETAR) bool {
+	if s.North != 0 || s.South != 0 || s.West != 0 || s.East != 0 {
+		return m.Latitude >= s.South && m.Latitude <= s.North && m.Longitude >= s.West && m.Longitude <= s.East
+	}
+	if stationGeoPt == nil {
+		return false
+	}
+	return stationGeoPt.GreatCircleDistance(geo.NewPoint(m.Latitude, m.Longitude)) <= METAR_RADIUS*KM_PER_SM
+}
+
+// mergeMETARs adds extra to metars, one report per station. Where both have a station the newer
+// observation wins. Returns the merged list and how many stations were new.
+func mergeMETARs(metars []ADDS.ADDSMETAR, extra []ADDS.ADDSMETAR) ([]ADDS.ADDSMETAR, int) {
+	idx := make(map[string]int)
+	for i, m := range metars {
+		idx[m.StationID] = i
+	}
+	added := 0
+	for _, m := range extra {
+		if i, ok := idx[m.StationID]; ok {
+			if m.Observation.Time.After(metars[i].Observation.Time) {
+				metars[i] = m
+			}
+			continue
+		}
+		idx[m.StationID] = len(metars)
+		metars = append(metars, m)
+		added++
+	}
+	return metars, added
+}
+
+// getExtraMETARs queries each configured METAR source and merges its reports into metars.
+func getExtraMETARs(metars []ADDS.ADDSMETAR, stats map[string]metarSourceStatus) []ADDS.ADDSMETAR {
+	for _, s := range globalSettings.METARSources {
+		reports, err := ADDS.GetADDSMETARsByIdent(s.IdentPrefix)
+		if err != nil {
+			log.Printf("METAR source %s: %s\n", s.Name, err.Error())
+			continue
+		}
+		var selected []ADDS.ADDSMETAR
+		for _, m := range reports {
+			if metarSourceSelects(s, m) {
+				selected = append(selected, m)
+			}
+		}
+		var added int
+		metars, added = mergeMETARs(metars, selected)
+		stats[s.Name] = metarSourceStatus{Fetched: len(selected), Added: added}
+	}
+	return metars
+}
+
+// sendBeaconMessage sends the beacon of one radio, advertising its own hop schedule.
+func sendBeaconMessage(r *txRadio) error {
+	plan := currentBandPlan()
+	slot := currentHop(r.scheme(), gpsNow())
 	serverStatus := &txwx.ServerStatus{
 		TimeOk:                 Location.GPSFixQuality > 0,
 		WeatherUpdatesOk:       len(allMETARs) > 0, //FIXME.
 		MetarsTracked:          uint32(len(allMETARs)),
 		TafsTracked:            uint32(len(allTAFs)),
-		FreqBandStart:          902,                           //FIXME.
-		FreqBandEnd:            928,                           //FIXME.
-		FreqSchemeList:         []uint32{(915 - 902) * 65536}, //FIXME: 915 MHz fixed, for now.
-		FreqSchemeDwell:        []uint32{10000},               //FIXME.
-		FreqSchemeModmode:      []uint32{1},                   //FIXME.
-		FreqSchemeCurrentIndex: 0,                             //FIXME.
+		AdvisoriesTracked:      uint32(len(allAIRSIGMETs)),
+		NotamsTracked:          uint32(len(allNOTAMs)),
+		TfrsTracked:            uint32(len(allTFRs)),
+		AirtimeUsed:            uint32(txLimiter.usedLastHour() / time.Millisecond),
+		DutyCycleLimit:         uint32(dutyCycleFor(slot.Entry.Freq) * 10000),
+		FreqBandStart:          uint32(plan.Start),
+		FreqBandEnd:            uint32(math.Ceil(plan.End)),
+		BandPlan:               plan.Name,
+		FreqSchemeCurrentIndex: uint32(slot.Index),
+		RadioIndex:             uint32(r.Index),
+		RadioCount:             uint32(len(txRadios)),
+	}
+	if tdmaEnabled() {
+		serverStatus.TdmaFrame = uint32(globalSettings.TDMAFrame)
+		serverStatus.TdmaSlots = uint32(globalSettings.TDMASlots)
+		serverStatus.TdmaSlot = uint32(tdmaOwnSlot())
+		serverStatus.TdmaGuard = uint32(globalSettings.TDMAGuard)
+	}
+	for _, e := range r.scheme() {
+		// 16.16 fixed point MHz above the band start.
+		serverStatus.FreqSchemeList = append(serverStatus.FreqSchemeList, uint32((e.Freq-float64(serverStatus.FreqBandStart))*65536))
+		serverStatus.FreqSchemeDwell = append(serverStatus.FreqSchemeDwell, uint32(e.Dwell))
+		serverStatus.FreqSchemeModmode = append(serverStatus.FreqSchemeModmode, uint32(e.ModMode))
+		band, _ := currentBandPlan().subBandOf(e.Freq)
+		serverStatus.FreqSchemeAirtime = append(serverStatus.FreqSchemeAirtime, uint32(txLimiter.usedIn(band)/time.Millisecond))
+		serverStatus.FreqSchemeDutyCycle = append(serverStatus.FreqSchemeDutyCycle, uint32(dutyCycleFor(e.Freq)*10000))
 	}
 	msg := &txwx.WeatherMessage{
 		Type:            txwx.WeatherMessage_BEACON,
-		TxTime:          uint32(time.Now().Unix()),
+		TxTime:          uint32(sysClock.Now().Unix()),
 		StationLat:      Location.GPSLatitude,
 		StationLng:      Location.GPSLongitude,
-		ObservationTime: uint32(time.Now().Unix()),
+		ObservationTime: uint32(sysClock.Now().Unix()),
 		ServerStatus:    serverStatus,
 	}
-	return txWeatherMessage(u, msg)
+	return txWeatherMessageOn([]*txRadio{r}, msg)
+}
+
+// beaconTimer sends the beacons every BEACON_TIME, or the BEACON Cadence. They jump the radios'
+// queues, so they go out on time no matter how long the carousel is.
+func beaconTimer() {
+	for {
+		<-sysClock.After(sendInterval(txwx.WeatherMessage_BEACON, BEACON_TIME))
+		sendBeaconMessages()
+	}
+}
+
+func sendBeaconMessages() {
+	for _, r := range txRadios {
+		err := sendBeaconMessage(r)
+		if err != nil {
+			log.Printf("beacon %s: %s\n", r.Config.Name, err.Error())
+		}
+	}
 }
 
 func preparePacketFromWeatherMessage(msg *txwx.WeatherMessage) []byte {
+	msg.StationId = stationIDHash()
 	data, err := proto.Marshal(msg)
 	if err != nil {
 		panic(err)
@@ -105,56 +284,334 @@ func preparePacketFromWeatherMessage(msg *txwx.WeatherMessage) []byte {
 	return data
 }
 
-func txWeatherMessage(u *uatradio.UATRadio, msg *txwx.WeatherMessage) error {
-	data := preparePacketFromWeatherMessage(msg)
-	if len(data) < 150 {
-		u.TX(data)
-		globalStatus.MessagesSent++
+// emergencyActive checks if any emergency notice is on the air at t.
+func emergencyActive(t time.Time) bool {
+	lookupMutex.Lock()
+	defer lookupMutex.Unlock()
+	for _, e := range allEmergencies {
+		if e.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// txWeatherMessage queues a message on the radio(s) carrying its product.
+func txWeatherMessage(msg *txwx.WeatherMessage) error {
+	return txWeatherMessageOn(radiosFor(msg.Type), msg)
+}
+
+func txWeatherMessageOn(radios []*txRadio, msg *txwx.WeatherMessage) error {
+	if preempted(msg.Type) || expired(msg, sysClock.Now()) {
+		return nil
+	}
+	return queuePacket(radios, msg.Type, preparePacketFromWeatherMessage(msg))
+}
+
+// txCachedPacket queues a pre-encoded report on radio, stamped with the current time.
+func txCachedPacket(radio *txRadio, p *cachedPacket) error {
+	if preempted(p.Msg.Type) || expired(p.Msg, sysClock.Now()) {
 		return nil
 	}
-	return errors.New("txWeatherMessage(): Message too long.")
+	return queuePacket([]*txRadio{radio}, p.Msg.Type, p.packet(uint32(sysClock.Now().Unix())))
+}
+
+// preempted checks if a message has to give way to an active emergency. The rest of the carousel pass
+// is dropped the same way, and the main loop goes back to repeating the emergency.
+func preempted(t txwx.WeatherMessage_Type) bool {
+	if t != txwx.WeatherMessage_EMERGENCY && t != txwx.WeatherMessage_BEACON && emergencyActive(sysClock.Now()) {
+		atomic.AddUint64(&globalStatus.MessagesPreempted, 1)
+		return true
+	}
+	return false
+}
+
+func queuePacket(radios []*txRadio, t txwx.WeatherMessage_Type, data []byte) error {
+	if len(data) >= MAX_PACKET_LEN {
+		return errors.New("txWeatherMessage(): Message too long.")
+	}
+	for _, r := range radios {
+		r.queue.push(txPacket{Data: data, Type: t}, priorityFor(t)) // Carousel messages block while the queue is full.
+	}
+	return nil
+}
+
+// packetSize is the length msg will have on the air at most, whenever and wherever it is sent. For
+// checking operator supplied products when they are loaded, rather than on every send.
+func packetSize(msg *txwx.WeatherMessage) int {
+	m := proto.Clone(msg).(*txwx.WeatherMessage)
+	m.TxTime = math.MaxUint32
+	m.StationLat, m.StationLng, m.StationId = 1, 1, 1 // Fixed size, but left out when 0.
+	return proto.Size(m) + PACKET_HEADER_LEN
 }
 
+// updateWeather refreshes the ADDS products, each every REPORTS_UPDATE_TIME or its FetchInterval.
 func updateWeather() {
-	updateTicker := time.NewTicker(REPORTS_UPDATE_TIME)
+	next := make(map[txwx.WeatherMessage_Type]time.Time) // Next refresh, by product.
+	due := func(t txwx.WeatherMessage_Type, now time.Time) bool {
+		if now.Before(next[t]) {
+			return false
+		}
+		next[t] = now.Add(fetchInterval(t, REPORTS_UPDATE_TIME))
+		return true
+	}
 	for {
 		for stationGeoPt == nil {
 			log.Printf("Waiting for GPS position from Stratux...\n")
-			time.Sleep(15 * time.Second)
+			sysClock.Sleep(15 * time.Second)
 		}
-		var metars []ADDS.ADDSMETAR
-		var tafs []ADDS.ADDSTAF
+		// Products not due keep their last set.
+		lookupMutex.Lock()
+		metars := allMETARs
+		sourceStats := metarSourceStats
+		tafs := allTAFs
+		airsigmets := allAIRSIGMETs
+		gairmets := allGAIRMETs
+		lookupMutex.Unlock()
+		now := sysClock.Now()
 		var err error
-		if txMetars { // Only request METARs when METAR TX is enabled.
-			// Get all METARs within 500 sm.
-			metars, err = ADDS.GetLatestADDSMETARsInRadiusOf(500, stationGeoPt)
+		if (txMetars || txWinds || txDerived || txATIS) && due(txwx.WeatherMessage_METAR, now) { // Only request METARs when METAR TX is enabled. Winds aloft use them to locate FB stations.
+			sourceStats = make(map[string]metarSourceStatus)
+			// Get all METARs within METAR_RADIUS sm.
+			metars, err = ADDS.GetLatestADDSMETARsInRadiusOf(METAR_RADIUS, stationGeoPt)
 			if err != nil {
 				panic(err)
 			}
+			// The radius query can repeat a station, dedupe it too.
+			metars, _ = mergeMETARs(nil, metars)
+			sourceStats["radius"] = metarSourceStatus{Fetched: len(metars), Added: len(metars)}
+			metars = getExtraMETARs(metars, sourceStats)
 		}
-		if txTafs { // Only request TAFs when TAF TX is enabled.
-			// Get all TAFs within 500 sm.
-			tafs, err = ADDS.GetLatestADDSTAFsInRadiusOf(500, stationGeoPt)
+		if txTafs && due(txwx.WeatherMessage_TAF, now) { // Only request TAFs when TAF TX is enabled.
+			// Get all TAFs within METAR_RADIUS sm.
+			tafs, err = ADDS.GetLatestADDSTAFsInRadiusOf(METAR_RADIUS, stationGeoPt)
 			if err != nil {
 				panic(err)
 			}
 		}
+		if txSigmets && due(txwx.WeatherMessage_SIGMET, now) { // Only request AIRMETs/SIGMETs when AIRMET/SIGMET TX is enabled.
+			airsigmets, err = getAIRSIGMETsInRadiusOf(globalSettings.CoverageRadius, stationGeoPt)
+			if err != nil {
+				// Keep the last good set rather than going dark on a feed hiccup.
+				log.Printf("AIRMET/SIGMET update failed: %s\n", err.Error())
+				airsigmets = allAIRSIGMETs
+			}
+		}
+		if txGairmets && due(txwx.WeatherMessage_GAIRMET, now) {
+			source := globalSettings.GAIRMETSource
+			if len(source) == 0 {
+				source = GAIRMET_URL
+			}
+			gairmets, err = getGAIRMETsInRadiusOf(source, globalSettings.CoverageRadius, stationGeoPt)
+			if err != nil {
+				log.Printf("G-AIRMET update failed: %s\n", err.Error())
+				gairmets = allGAIRMETs
+			}
+		}
 		lookupMutex.Lock()
 		allMETARs = metars
+		metarSourceStats = sourceStats
 		allTAFs = tafs
+		allAIRSIGMETs = airsigmets
+		allGAIRMETs = gairmets
+		if fp := reportsFingerprint(metars, tafs); fp != weatherFingerprint {
+			weatherFingerprint = fp
+			weatherGeneration++
+		}
 		lookupMutex.Unlock()
 
-		<-updateTicker.C
+		wake := now.Add(REPORTS_UPDATE_TIME)
+		for _, t := range next {
+			if t.Before(wake) {
+				wake = t
+			}
+		}
+		sysClock.Sleep(wake.Sub(sysClock.Now()))
+	}
+}
+
+// getWindsAloft reads the FB winds aloft bulletin from source, a local file or an http(s) URL.
+func getWindsAloft(source string) ([]windsAloftStation, error) {
+	body, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return parseFBBulletin(bytes.NewReader(body), sysClock.Now())
+}
+
+func updateWindsAloft() {
+	for {
+		source := globalSettings.WindsAloftSource
+		if len(source) == 0 {
+			source = WINDS_ALOFT_URL
+		}
+		stations, err := getWindsAloft(source)
+		if err != nil {
+			log.Printf("winds aloft update failed: %s\n", err.Error())
+		} else {
+			lookupMutex.Lock()
+			allWindsAloft = stations
+			lookupMutex.Unlock()
+		}
+		<-sysClock.After(fetchInterval(txwx.WeatherMessage_WINDS_ALOFT, WINDS_UPDATE_TIME))
 	}
 }
 
+// reloadEvery calls load right away and then every interval, to pick up changes to an operator file
+// or feed. Errors are logged once until they change, a missing file is the normal state for most
+// stations.
+func reloadEvery(interval time.Duration, what, source string, load func() error) {
+	lastErr := ""
+	for {
+		err := load()
+		if err != nil && err.Error() != lastErr {
+			log.Printf("can't load %s %s: %s\n", what, source, err.Error())
+		}
+		lastErr = ""
+		if err != nil {
+			lastErr = err.Error()
+		}
+		<-sysClock.After(interval)
+	}
+}
+
+// updateNOTAMs re-reads the operator's NOTAM file periodically, so edits are picked up without a restart.
+func updateNOTAMs() {
+	reloadEvery(fetchInterval(txwx.WeatherMessage_NOTAM, LOCAL_RELOAD_TIME), "NOTAMs", globalSettings.NOTAMPath, func() error {
+		notams, err := loadNOTAMs(globalSettings.NOTAMPath)
+		lookupMutex.Lock()
+		allNOTAMs = notams
+		lookupMutex.Unlock()
+		return err
+	})
+}
+
+// updateTFRs re-reads the operator's TFR file periodically, keeping only TFRs near the station.
+func updateTFRs() {
+	reloadEvery(fetchInterval(txwx.WeatherMessage_TFR, LOCAL_RELOAD_TIME), "TFRs", globalSettings.TFRPath, func() error {
+		tfrs, err := loadTFRs(globalSettings.TFRPath)
+		var nearby []TFR
+		for _, t := range tfrs {
+			if t.InRadiusOf(globalSettings.CoverageRadius, stationGeoPt) {
+				nearby = append(nearby, t)
+			}
+		}
+		lookupMutex.Lock()
+		allTFRs = nearby
+		lookupMutex.Unlock()
+		return err
+	})
+}
+
+// updateBulletins re-reads the operator's bulletins periodically.
+func updateBulletins() {
+	reloadEvery(fetchInterval(txwx.WeatherMessage_BULLETIN, LOCAL_RELOAD_TIME), "bulletins", globalSettings.BulletinSource, func() error {
+		bulletins, err := loadBulletins(globalSettings.BulletinSource)
+		lookupMutex.Lock()
+		allBulletins = bulletins
+		lookupMutex.Unlock()
+		return err
+	})
+}
+
+// updateRadar re-cuts the radar tiles whenever the operator drops in a new image.
+func updateRadar() {
+	reloadEvery(fetchInterval(txwx.WeatherMessage_RADAR, LOCAL_RELOAD_TIME), "radar image", globalSettings.RadarPath, func() error {
+		fi, err := os.Stat(globalSettings.RadarPath)
+		if err != nil || fi.ModTime().Equal(radarTime) || stationGeoPt == nil {
+			return err
+		}
+		r, err := loadRadarRaster(globalSettings.RadarPath)
+		if err != nil {
+			return err
+		}
+		tiles := radarTiles(r, stationGeoPt)
+		lookupMutex.Lock()
+		allRadarTiles = tiles
+		radarTime = r.Time
+		lookupMutex.Unlock()
+		log.Printf("radar image %s loaded, %d tiles.\n", globalSettings.RadarPath, len(tiles))
+		return nil
+	})
+}
+
+// updateATISOverrides re-reads the operator's runway in use overrides.
+func updateATISOverrides() {
+	reloadEvery(fetchInterval(txwx.WeatherMessage_ATIS, LOCAL_RELOAD_TIME), "runway overrides", globalSettings.ATISOverridePath, func() error {
+		overrides, err := loadATISOverrides(globalSettings.ATISOverridePath)
+		lookupMutex.Lock()
+		allATISOverrides = overrides
+		lookupMutex.Unlock()
+		return err
+	})
+}
+
+// updateEmergencies watches the operator's emergency file. It is checked every EMERGENCY_RELOAD_TIME
+// so that a new notice goes out within seconds.
+func updateEmergencies() {
+	active := make(map[string]bool)
+	reloadEvery(EMERGENCY_RELOAD_TIME, "emergencies", globalSettings.EmergencyPath, func() error {
+		emergencies, err := loadEmergencies(globalSettings.EmergencyPath)
+		if err != nil {
+			emergencies = nil
+		}
+		now := sysClock.Now()
+		for _, e := range emergencies {
+			if e.Active(now) && !active[e.Ident+e.Text] {
+				log.Printf("EMERGENCY %s on the air until %s: %s\n", e.Ident, e.End.UTC().Format(time.RFC3339), e.Text)
+			}
+		}
+		active = make(map[string]bool)
+		for _, e := range emergencies {
+			if e.Active(now) {
+				active[e.Ident+e.Text] = true
+			}
+		}
+		lookupMutex.Lock()
+		allEmergencies = emergencies
+		lookupMutex.Unlock()
+		if os.IsNotExist(err) {
+			return nil // No emergency.
+		}
+		return err
+	})
+}
+
 func printStats() {
-	statTimer := time.NewTicker(1 * time.Minute)
-	startTime := time.Now()
+	startTime := sysClock.Now()
 	for {
-		<-statTimer.C
-		log.Printf("stats [started: %s]\n", humanize.RelTime(startTime, time.Now(), "ago", "from now"))
-		log.Printf(" - Messages sent: %d, METARs tracked: %d, TAFs tracked: %d.\n", globalStatus.MessagesSent, len(allMETARs), len(allTAFs))
+		<-sysClock.After(1 * time.Minute)
+		log.Printf("stats [started: %s]\n", humanize.RelTime(startTime, sysClock.Now(), "ago", "from now"))
+		log.Printf(" - Messages sent: %d, emergencies sent: %d, preempted: %d.\n", atomic.LoadUint64(&globalStatus.MessagesSent), atomic.LoadUint64(&globalStatus.EmergenciesSent), atomic.LoadUint64(&globalStatus.MessagesPreempted))
+		log.Printf(" - Packet cache: %d hits, %d misses.\n", atomic.LoadUint64(&cacheStats.Hits), atomic.LoadUint64(&cacheStats.Misses))
+		if lbt := lbtStatsCopy(); len(globalSettings.LBTMode) > 0 && lbt.Samples > 0 {
+			log.Printf(" - Listen-before-talk: channel busy %0.1f%% of %d samples, %d deferrals, %d messages dropped.\n", 100*float64(lbt.Busy)/float64(lbt.Samples), lbt.Samples, lbt.Deferrals, lbt.GaveUp)
+		}
+		airtime := txLimiter.usedLastHour()
+		log.Printf(" - Airtime last hour: %s (%0.2f%%).\n", airtime, 100*airtime.Seconds()/DUTY_CYCLE_WINDOW.Seconds())
+		for _, b := range txLimiter.bandsUsed() {
+			used := txLimiter.usedIn(b)
+			log.Printf(" - Sub-band %0.3f-%0.3f MHz: %s on the air last hour (%0.2f%%, limit %0.2f%%).\n", b.Start, b.End, used, 100*used.Seconds()/DUTY_CYCLE_WINDOW.Seconds(), 100*dutyCycleFor(b.Start))
+		}
+		for _, r := range txRadios {
+			slot := currentHop(r.scheme(), gpsNow())
+			log.Printf(" - Radio %s: %d messages sent, %s on the air, now on %0.3f MHz (duty cycle limit %0.2f%%).\n", r.Config.Name, atomic.LoadUint64(&r.MessagesSent), r.Airtime, slot.Entry.Freq, 100*dutyCycleFor(slot.Entry.Freq))
+		}
+		newBytes, repeatBytes := atomic.LoadUint64(&globalStatus.NewReportBytes), atomic.LoadUint64(&globalStatus.RepeatReportBytes)
+		if total := newBytes + repeatBytes; total > 0 {
+			log.Printf(" - Carousel airtime: new reports %d bytes (%d%%), repeats %d bytes.\n", newBytes, newBytes*100/total, repeatBytes)
+		}
+		log.Printf(" - METARs tracked: %d, TAFs tracked: %d, AIRMETs/SIGMETs tracked: %d, G-AIRMETs: %d, winds aloft stations: %d, NOTAMs: %d, TFRs: %d, bulletins: %d, lightning strikes: %d.\n", len(allMETARs), len(allTAFs), len(allAIRSIGMETs), len(allGAIRMETs), len(allWindsAloft), len(allNOTAMs), len(allTFRs), len(allBulletins), len(recentStrikes))
+		lookupMutex.Lock()
+		sources := make([]string, 0, len(metarSourceStats))
+		for name, s := range metarSourceStats {
+			sources = append(sources, fmt.Sprintf("%s %d (%d new)", name, s.Fetched, s.Added))
+		}
+		lookupMutex.Unlock()
+		if len(sources) > 0 {
+			sort.Strings(sources)
+			log.Printf(" - METAR sources: %s.\n", strings.Join(sources, ", "))
+		}
 		log.Printf(" - Current location: (%0.4f, %0.4f).\n", Location.GPSLatitude, Location.GPSLongitude)
 	}
 }
@@ -164,6 +621,16 @@ func startup() {
 	flag.BoolVar(&beaconMode, "beaconMode", false, "Transmit beacons only.")
 	flag.BoolVar(&txMetars, "metars", true, "Transmit METARs. OFF in beaconMode, regardless of setting.")
 	flag.BoolVar(&txTafs, "tafs", true, "Transmit TAFs. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txSigmets, "sigmets", true, "Transmit AIRMETs/SIGMETs. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txWinds, "winds", true, "Transmit winds and temperatures aloft. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txNotams, "notams", true, "Transmit NOTAMs from the local NOTAM file. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txTfrs, "tfrs", true, "Transmit TFRs from the local TFR file. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txBulletins, "bulletins", true, "Transmit operator bulletins. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txRadar, "radar", true, "Transmit the local radar image. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txDerived, "derived", true, "Transmit flight category and density altitude derived from METARs. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&txGairmets, "gairmets", true, "Transmit G-AIRMETs, at a reduced rate. OFF in beaconMode, regardless of setting.")
+	flag.BoolVar(&debugCarousel, "debugCarousel", false, "Log the reports planned for each carousel pass, with their weights.")
+	flag.BoolVar(&txATIS, "atis", true, "Transmit runway in use for the airports in the runway table. OFF in beaconMode, regardless of setting.")
 
 	flag.Float64Var(&globalSettings.ManualLat, "lat", 0.0, "Station latitude. If entered with longitude, GPS data is not used.")
 	flag.Float64Var(&globalSettings.ManualLng, "lng", 0.0, "Station longitude. If entered with latitude, GPS data is not used.")
@@ -174,7 +641,6 @@ func startup() {
 
 func main() {
 	startup()
-	beaconTicker := time.NewTicker(BEACON_TIME)
 
 	setupLogging("/var/log/txwx.log") // Open logfile, set "log" output to save there and print to stdout.
 
@@ -185,13 +651,36 @@ func main() {
 		return
 	}
 
-	u, err := uatradio.NewUATRadio(globalSettings.Freq, globalSettings.RadioModMode, productID)
+	err := validateBandPlan()
+	if err == nil {
+		err = validateModModes()
+	}
+	if err == nil {
+		err = validateTDMA(longestPacketAirtime())
+	}
+	if err == nil {
+		err = validateCadence()
+	}
+	if err == nil {
+		err = validateAirports()
+	}
+	if err != nil {
+		log.Printf("Refusing to transmit: %s.\n", err.Error())
+		return
+	}
 
+	err = openRadios()
 	if err != nil {
 		log.Printf("Unable to open radio: %s\n", err.Error())
 		panic(err)
 	}
 
+	err = startLBT()
+	if err != nil {
+		log.Printf("Unable to open listen-before-talk radio: %s\n", err.Error())
+		panic(err)
+	}
+
 	crc64Table = crc64.MakeTable(crc64.ECMA)
 
 	if globalSettings.ManualLat != 0. || globalSettings.ManualLng != 0. {
@@ -203,47 +692,267 @@ func main() {
 	}
 
 	go situationUpdater() // Update current station position from Stratux.
-	if !beaconMode && (txMetars || txTafs) {
+	go beaconTimer()
+	if !beaconMode && (txMetars || txTafs || txSigmets || txWinds || txDerived || txATIS || txGairmets) {
 		go updateWeather() // Update weather data from ADDS.
 	}
+	if !beaconMode && txWinds {
+		go updateWindsAloft() // Update winds aloft forecasts, on their own slower schedule.
+	}
+	if !beaconMode && txNotams {
+		go updateNOTAMs() // Watch the local NOTAM file.
+	}
+	if !beaconMode && txTfrs {
+		go updateTFRs() // Watch the local TFR file.
+	}
+	if !beaconMode && txBulletins {
+		go updateBulletins() // Watch the bulletin file or endpoint.
+	}
+	if !beaconMode && txRadar {
+		go updateRadar() // Watch the local radar image.
+	}
+	// Emergencies go out in beaconMode too.
+	if len(globalSettings.EmergencyKey) > 0 {
+		go updateEmergencies() // Watch the emergency file.
+	} else {
+		log.Printf("No EmergencyKey set. Emergency broadcasts disabled.\n")
+	}
+	if !beaconMode && txATIS && len(globalSettings.Airports) > 0 {
+		go updateATISOverrides() // Watch the runway in use override file.
+	}
+	if !beaconMode && len(globalSettings.SensorSource) > 0 {
+		if len(globalSettings.SensorIdent) == 0 {
+			log.Printf("SensorSource is set but SensorIdent is not. Local sensor observations disabled.\n")
+		} else {
+			go readFeed("sensor", globalSettings.SensorSource, setSensorObservation) // Local weather station.
+		}
+	}
+	if !beaconMode && len(globalSettings.LightningSource) > 0 {
+		go readFeed("lightning", globalSettings.LightningSource, addLightningStrike) // Local strike feed.
+	}
 
 	log.Printf("Starting TX %s-%s.\n", txwxVersion, txwxBuild[:10])
 	go printStats() // Periodically print stats.
 
+	for _, r := range txRadios[1:] {
+		go runCarousel(r)
+	}
+	runCarousel(txRadios[0])
+}
+
+// runCarousel builds and queues the passes of the products radio carries. Every radio has its own, so
+// a radio held up by its duty cycle or TDMA slot doesn't hold up the others.
+func runCarousel(radio *txRadio) {
+	send := func(msg *txwx.WeatherMessage) error {
+		if !radio.sends(msg.Type) {
+			return nil
+		}
+		return txWeatherMessageOn([]*txRadio{radio}, msg)
+	}
+	bulletinLastSent := make(map[string]bulletinSent)
+	pass := 0
+	gairmetNext := 0 // Carousel position, kept across passes.
+	reportCarousel := newCarousel()
+	reportCache := newPacketCache()
+	var reports *reportSet // Rebuilt when the weather data changes.
+	carries := func(t txwx.WeatherMessage_Type) bool {
+		switch t {
+		case txwx.WeatherMessage_METAR:
+			return txMetars && radio.sends(t)
+		case txwx.WeatherMessage_TAF:
+			return txTafs && radio.sends(t)
+		case txwx.WeatherMessage_DERIVED:
+			return txDerived && radio.sends(t)
+		}
+		return false
+	}
+	cadence := newCadenceGate()
+
 	for {
+		pass++
 		lookupMutex.Lock()
 		metars := allMETARs
 		tafs := allTAFs
+		generation := weatherGeneration
+		airsigmets := allAIRSIGMETs
+		gairmets := allGAIRMETs
+		windsAloft := allWindsAloft
+		notams := allNOTAMs
+		tfrs := allTFRs
+		sensorObs := latestSensorObs
+		bulletins := allBulletins
+		pruneLightningStrikes(sysClock.Now())
+		strikes := append([]lightningStrike(nil), recentStrikes...)
+		radarTiles := allRadarTiles
+		radarObservationTime := radarTime
+		atisOverrides := allATISOverrides
+		emergencies := allEmergencies
 		lookupMutex.Unlock()
 
+		// An active emergency takes over the channel: only it and the beacon go out until it ends.
+		now := sysClock.Now()
+		if sendEmergencies(emergencies, now, send) {
+			sysClock.Sleep(time.Duration(globalSettings.EmergencyInterval) * time.Second)
+			continue
+		}
+
+		// Bulletins have their own repeat intervals. They are checked between weather reports so that a
+		//  long METAR/TAF pass doesn't hold them up.
+		sendDueBulletins := func() {
+			if beaconMode || !txBulletins || !radio.sends(txwx.WeatherMessage_BULLETIN) {
+				return
+			}
+			for _, v := range dueBulletins(bulletins, bulletinLastSent, pass) {
+				msg := createBulletinWeatherMessage(v)
+				err := send(msg)
+				if err != nil {
+					log.Printf("bulletin %s: %s\n", v.Ident, err.Error())
+				}
+			}
+		}
+
+		// G-AIRMETs are many and change slowly. One snapshot goes out per GAIRMETWeight reports, picking
+		//  up where the last pass left off, so they cycle through without crowding out the METARs.
+		sendNextGAIRMET := func() {
+			if beaconMode || !txGairmets || len(gairmets) == 0 || !radio.sends(txwx.WeatherMessage_GAIRMET) {
+				return
+			}
+			now := sysClock.Now()
+			for i := 0; i < len(gairmets); i++ {
+				v := gairmets[gairmetNext%len(gairmets)]
+				gairmetNext = (gairmetNext + 1) % len(gairmets)
+				if now.After(v.ExpireTime) {
+					continue
+				}
+				msg := createGAIRMETWeatherMessage(v)
+				err := send(msg)
+				if err != nil {
+					log.Printf("G-AIRMET %s: %s\n", msg.Ident, err.Error())
+				}
+				return
+			}
+		}
+
 		if !beaconMode {
+			// METARs and TAFs share the weighted carousel.
+			if reports.stale(generation) {
+				reports = newReportSet(generation, metars, tafs, carries, reportCache)
+			}
+			current := reports.current(now)
+			due := reportCarousel.plan(current, now)
+			if debugCarousel {
+				logCarousel(due, len(current))
+			}
+			for i, r := range due {
+				err := txCachedPacket(radio, r.Packet)
+				if err == nil {
+					size := uint64(len(r.Packet.Body) + PACKET_HEADER_LEN)
+					if r.New {
+						atomic.AddUint64(&globalStatus.NewReportBytes, size)
+					} else {
+						atomic.AddUint64(&globalStatus.RepeatReportBytes, size)
+					}
+				}
+				sendDueBulletins()
+				if globalSettings.GAIRMETWeight > 0 && (i+1)%globalSettings.GAIRMETWeight == 0 {
+					sendNextGAIRMET()
+				}
+			}
+
 			if txMetars {
-				for _, v := range metars {
-					msg := createMETARWeatherMessage(v)
-					txWeatherMessage(u, msg)
+				if sensorObs != nil && sysClock.Now().Sub(sensorObs.Time) < SENSOR_MAX_AGE {
+					msg := createSensorWeatherMessage(sensorObs)
+					send(msg)
 				}
 			}
 
-			if txTafs {
-				for _, v := range tafs {
-					if v.Text[:4] == "TAF" {
-						v.Text = v.Text[4:]
+			if txDerived && radio.sends(txwx.WeatherMessage_DERIVED) && cadence.due(txwx.WeatherMessage_DERIVED, now) {
+				for _, r := range reports.derived {
+					if tooOld(txwx.WeatherMessage_METAR, r.Observed, now) {
+						continue // No fresher than the METAR it is derived from.
+					}
+					txCachedPacket(radio, r.Packet)
+					sendDueBulletins()
+				}
+			}
+
+			if txATIS && radio.sends(txwx.WeatherMessage_ATIS) && cadence.due(txwx.WeatherMessage_ATIS, now) {
+				for _, msg := range createATISWeatherMessages(metars, atisOverrides) {
+					err := send(msg)
+					if err != nil {
+						log.Printf("runway in use %s: %s\n", msg.Ident, err.Error())
+					}
+				}
+			}
+
+			if txSigmets && cadence.due(txwx.WeatherMessage_SIGMET, now) {
+				for _, v := range airsigmets {
+					if sysClock.Now().After(v.ValidTimeTo) {
+						continue // Expired since the last update.
+					}
+					msg := createAIRSIGMETWeatherMessage(v)
+					err := send(msg)
+					if err != nil {
+						log.Printf("AIRMET/SIGMET %s: %s\n", msg.Ident, err.Error())
 					}
-					msg := createTAFWeatherMessage(v)
-					txWeatherMessage(u, msg)
 				}
 			}
-		}
 
-		select {
-		case <-beaconTicker.C:
-			// Time to send a beacon message.
-			sendBeaconMessage(u)
-		default:
-			// Default case so that the select doesn't block.
-			//  In reality, if BEACON_TIME is shorter than it takes to transmit all weather data,
-			//  a beacon message will be sent out on each loop.
+			if txWinds && radio.sends(txwx.WeatherMessage_WINDS_ALOFT) && cadence.due(txwx.WeatherMessage_WINDS_ALOFT, now) {
+				for _, v := range nearbyWindsAloft(windsAloft, metars) {
+					msg := createWindsAloftWeatherMessage(v)
+					send(msg)
+				}
+			}
+
+			if txNotams && radio.sends(txwx.WeatherMessage_NOTAM) && cadence.due(txwx.WeatherMessage_NOTAM, now) {
+				now := sysClock.Now()
+				for _, v := range notams {
+					if !v.Active(now) {
+						continue // Not yet effective, or expired.
+					}
+					msg := createNOTAMWeatherMessage(v)
+					err := send(msg)
+					if err != nil {
+						log.Printf("NOTAM %s: %s\n", v.Ident, err.Error())
+					}
+				}
+			}
+
+			if txTfrs && radio.sends(txwx.WeatherMessage_TFR) && cadence.due(txwx.WeatherMessage_TFR, now) {
+				now := sysClock.Now()
+				for _, v := range tfrs {
+					if !v.Active(now) {
+						continue
+					}
+					msg := createTFRWeatherMessage(v)
+					err := send(msg)
+					if err != nil {
+						log.Printf("TFR %s: %s\n", v.Ident, err.Error())
+					}
+				}
+			}
+
+			if len(globalSettings.LightningSource) > 0 && radio.sends(txwx.WeatherMessage_LIGHTNING) && cadence.due(txwx.WeatherMessage_LIGHTNING, now) {
+				for _, msg := range createLightningWeatherMessages(strikes) {
+					send(msg)
+				}
+			}
+
+			if txRadar && radio.sends(txwx.WeatherMessage_RADAR) && cadence.due(txwx.WeatherMessage_RADAR, now) {
+				for _, t := range radarTiles {
+					msg := createRadarWeatherMessage(t, radarObservationTime)
+					send(msg)
+					sendDueBulletins()
+				}
+			}
+
+			sendNextGAIRMET() // At least one per pass, even with few or no METARs.
+			sendDueBulletins()
 		}
-		time.Sleep(100 * time.Millisecond)
+
+		// Let the pass go out before building the next one, so it is built from fresh data.
+		radio.queue.waitIdle()
+		sysClock.Sleep(CAROUSEL_PAUSE)
 	}
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"./proto"
+	"bytes"
+	"errors"
+	"github.com/cyoung/ADDS"
+	"hash/crc64"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBeaconTimer(t *testing.T) {
+	resetSettings()
+	crc64Table = crc64.MakeTable(crc64.ECMA)
+	lookupMutex = &sync.Mutex{}
+	c := newFakeClock(time.Unix(4000, 0))
+	defer useClock(c)()
+	r := testRadio(nil)
+	old := txRadios
+	txRadios = []*txRadio{r}
+	defer func() { txRadios = old }()
+
+	// A full carousel doesn't hold the beacon up.
+	for i := 0; i < RADIO_QUEUE_LEN; i++ {
+		r.queue.push(testPacket(txwx.WeatherMessage_METAR, byte(i)), PRIORITY_CAROUSEL)
+	}
+	go beaconTimer()
+	c.waitTimers(1)
+	c.advance(BEACON_TIME / 2)
+	if p := r.queue.pop(); p.Type == txwx.WeatherMessage_BEACON {
+		t.Fatalf("beacon before BEACON_TIME")
+	}
+	c.advance(BEACON_TIME / 2)
+	if p := r.queue.pop(); p.Type != txwx.WeatherMessage_BEACON {
+		t.Fatalf("no beacon at BEACON_TIME, got %s", p.Type)
+	}
+	c.advance(BEACON_TIME)
+	if p := r.queue.pop(); p.Type != txwx.WeatherMessage_BEACON {
+		t.Fatalf("no second beacon, got %s", p.Type)
+	}
+}
+
+func TestNoExtraMETARSourcesByDefault(t *testing.T) {
+	resetSettings()
+	metars := []ADDS.ADDSMETAR{{StationID: "KXYZ"}}
+	stats := make(map[string]metarSourceStatus)
+	if got := getExtraMETARs(metars, stats); len(got) != 1 || len(stats) != 0 {
+		t.Errorf("queried extra METAR sources without any configured: %+v", stats)
+	}
+}
+
+func TestMergeMETARs(t *testing.T) {
+	at := func(min int) ADDS.ADDSTime { return ADDS.ADDSTime{Time: time.Unix(int64(min)*60, 0)} }
+	metars := []ADDS.ADDSMETAR{{StationID: "KXYZ", Text: "old", Observation: at(10)}, {StationID: "KABC", Text: "abc", Observation: at(10)}}
+	extra := []ADDS.ADDSMETAR{
+		{StationID: "KXYZ", Text: "new", Observation: at(20)},
+		{StationID: "KABC", Text: "older", Observation: at(5)},
+		{StationID: "CYXU", Text: "cyxu", Observation: at(10)},
+	}
+	merged, added := mergeMETARs(metars, extra)
+	if added != 1 || len(merged) != 3 {
+		t.Fatalf("added %d, merged %d", added, len(merged))
+	}
+	for i, want := range []string{"new", "abc", "cyxu"} {
+		if merged[i].Text != want {
+			t.Errorf("merged[%d] = %s, want %s", i, merged[i].Text, want)
+		}
+	}
+}
+
+func TestReloadEveryLogsErrorsOnce(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	c := newFakeClock(time.Unix(5000, 0))
+	defer useClock(c)()
+	results := []error{errors.New("missing"), errors.New("missing"), nil, errors.New("missing"), errors.New("bad")}
+	calls := make(chan int, len(results))
+	go reloadEvery(time.Minute, "things", "/tmp/things.json", func() error {
+		n := len(calls)
+		calls <- n
+		if n < len(results) {
+			return results[n]
+		}
+		return nil
+	})
+	for i := 0; i < len(results); i++ {
+		c.waitTimers(1)
+		c.advance(time.Minute)
+	}
+	if n := len(calls); n < len(results) {
+		t.Fatalf("load called %d times", n)
+	}
+	if got := strings.Count(buf.String(), "can't load things /tmp/things.json"); got != 3 {
+		t.Errorf("logged %d errors, want 3:\n%s", got, buf.String())
+	}
+}
+
+func TestGetWindsAloftFromFile(t *testing.T) {
+	c := newFakeClock(time.Date(2019, 10, 19, 14, 5, 0, 0, time.UTC))
+	defer useClock(c)()
+	dir, err := ioutil.TempDir("", "winds")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "fb.txt")
+	if err := ioutil.WriteFile(fn, []byte(testFBBulletin), 0644); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	stations, err := getWindsAloft(fn)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if len(stations) != 5 || stations[3].Ident != "DEN" {
+		t.Errorf("read %d stations", len(stations))
+	}
+	if _, err := getWindsAloft(filepath.Join(dir, "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"./proto"
+	"container/heap"
+	"sync"
+)
+
+// Transmit priorities, lowest first.
+const (
+	PRIORITY_EMERGENCY = iota
+	PRIORITY_BEACON
+	PRIORITY_BULLETIN
+	PRIORITY_CAROUSEL
+)
+
+func priorityFor(t txwx.WeatherMessage_Type) int {
+	switch t {
+	case txwx.WeatherMessage_EMERGENCY:
+		return PRIORITY_EMERGENCY
+	case txwx.WeatherMessage_BEACON:
+		return PRIORITY_BEACON
+	case txwx.WeatherMessage_BULLETIN:
+		return PRIORITY_BULLETIN
+	}
+	return PRIORITY_CAROUSEL
+}
+
+type queuedPacket struct {
+	Packet   txPacket
+	Priority int
+	Seq      uint64 // FIFO within a priority.
+}
+
+// packetHeap implements heap.Interface.
+type packetHeap []queuedPacket
+
+func (h packetHeap) Len() int { return len(h) }
+func (h packetHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority < h[j].Priority
+	}
+	return h[i].Seq < h[j].Seq
+}
+func (h packetHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *packetHeap) Push(x interface{}) { *h = append(*h, x.(queuedPacket)) }
+func (h *packetHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// txQueue is a radio's priority queue. Carousel packets and bulletins wait for room, so they go at
+// the radio's pace, bulletins still ahead of the carousel. Only the latest beacon is kept, and
+// emergencies are always taken. Beacons and emergencies go out next.
+type txQueue struct {
+	mu    *sync.Mutex
+	cond  *sync.Cond
+	items packetHeap
+	seq   uint64
+	limit int
+	busy  bool // A popped packet is being transmitted.
+}
+
+func newTxQueue(limit int) *txQueue {
+	q := &txQueue{mu: &sync.Mutex{}, limit: limit}
+	q.cond = sync.NewCond(q.mu)
+	return q
+}
+
+func (q *txQueue) push(p txPacket, priority int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for priority >= PRIORITY_BULLETIN && len(q.items) >= q.limit {
+		q.cond.Wait()
+	}
+	switch priority {
+	case PRIORITY_EMERGENCY:
+		// Whatever carousel traffic is queued is stale by the time the emergency is over.
+		q.remove(func(v queuedPacket) bool { return v.Priority == PRIORITY_CAROUSEL })
+	case PRIORITY_BEACON:
+		// A beacon still waiting for the radio has an old time and hop index, this one replaces it.
+		q.remove(func(v queuedPacket) bool { return v.Priority == PRIORITY_BEACON })
+	}
+	q.seq++
+	heap.Push(&q.items, queuedPacket{Packet: p, Priority: priority, Seq: q.seq})
+	q.cond.Broadcast()
+}
+
+// remove drops the queued packets drop is true for. The caller holds q.mu.
+func (q *txQueue) remove(drop func(queuedPacket) bool) {
+	kept := q.items[:0]
+	for _, v := range q.items {
+		if !drop(v) {
+			kept = append(kept, v)
+		}
+	}
+	q.items = kept
+	heap.Init(&q.items)
+}
+
+// pop waits for the most urgent packet.
+func (q *txQueue) pop() txPacket {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.busy = false
+	q.cond.Broadcast()
+	for len(q.items) == 0 {
+		q.cond.Wait()
+	}
+	q.busy = true
+	return heap.Pop(&q.items).(queuedPacket).Packet
+}
+
+// waitIdle blocks until everything queued has gone out.
+func (q *txQueue) waitIdle() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) > 0 || q.busy {
+		q.cond.Wait()
+	}
+}
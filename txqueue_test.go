@@ -0,0 +1,135 @@
+package main
+
+import (
+	"./proto"
+	"testing"
+	"time"
+)
+
+func testPacket(t txwx.WeatherMessage_Type, b byte) txPacket {
+	return txPacket{Data: []byte{b}, Type: t}
+}
+
+func TestTxQueueOrder(t *testing.T) {
+	q := newTxQueue(16)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 1), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_BULLETIN, 2), PRIORITY_BULLETIN)
+	q.push(testPacket(txwx.WeatherMessage_TAF, 3), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 4), PRIORITY_BEACON)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 5), PRIORITY_CAROUSEL)
+	want := []byte{4, 2, 1, 3, 5} // By priority, FIFO within one.
+	for _, w := range want {
+		if p := q.pop(); p.Data[0] != w {
+			t.Fatalf("popped %d, want %d", p.Data[0], w)
+		}
+	}
+}
+
+func TestTxQueueEmergencyDropsCarousel(t *testing.T) {
+	q := newTxQueue(16)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 1), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 2), PRIORITY_BEACON)
+	q.push(testPacket(txwx.WeatherMessage_EMERGENCY, 3), PRIORITY_EMERGENCY)
+	q.push(testPacket(txwx.WeatherMessage_TAF, 4), PRIORITY_CAROUSEL)
+	want := []byte{3, 2, 4}
+	for _, w := range want {
+		if p := q.pop(); p.Data[0] != w {
+			t.Fatalf("popped %d, want %d", p.Data[0], w)
+		}
+	}
+	if len(q.items) != 0 {
+		t.Errorf("%d packets left", len(q.items))
+	}
+}
+
+func TestTxQueueBackpressure(t *testing.T) {
+	q := newTxQueue(2)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 1), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 2), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 3), PRIORITY_BEACON) // Never waits.
+	pushed := make(chan bool)
+	go func() {
+		q.push(testPacket(txwx.WeatherMessage_METAR, 4), PRIORITY_CAROUSEL)
+		pushed <- true
+	}()
+	select {
+	case <-pushed:
+		t.Fatalf("carousel push to a full queue didn't wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	q.pop() // The beacon, the queue is still full of carousel packets.
+	select {
+	case <-pushed:
+		t.Fatalf("carousel push to a full queue didn't wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	q.pop()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatalf("carousel push didn't resume")
+	}
+}
+
+func TestTxQueueKeepsLatestBeacon(t *testing.T) {
+	q := newTxQueue(16)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 1), PRIORITY_BEACON)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 2), PRIORITY_CAROUSEL)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 3), PRIORITY_BEACON)
+	q.push(testPacket(txwx.WeatherMessage_BEACON, 4), PRIORITY_BEACON)
+	want := []byte{4, 2}
+	for _, w := range want {
+		if p := q.pop(); p.Data[0] != w {
+			t.Fatalf("popped %d, want %d", p.Data[0], w)
+		}
+	}
+	if len(q.items) != 0 {
+		t.Errorf("%d packets left", len(q.items))
+	}
+}
+
+func TestTxQueueBulletinsWait(t *testing.T) {
+	q := newTxQueue(2)
+	q.push(testPacket(txwx.WeatherMessage_BULLETIN, 1), PRIORITY_BULLETIN)
+	q.push(testPacket(txwx.WeatherMessage_BULLETIN, 2), PRIORITY_BULLETIN)
+	pushed := make(chan bool)
+	go func() {
+		q.push(testPacket(txwx.WeatherMessage_BULLETIN, 3), PRIORITY_BULLETIN)
+		pushed <- true
+	}()
+	select {
+	case <-pushed:
+		t.Fatalf("bulletin push to a full queue didn't wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	q.push(testPacket(txwx.WeatherMessage_EMERGENCY, 4), PRIORITY_EMERGENCY) // Never waits.
+	q.pop()
+	q.pop()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatalf("bulletin push didn't resume")
+	}
+}
+
+func TestTxQueueWaitIdle(t *testing.T) {
+	q := newTxQueue(16)
+	q.push(testPacket(txwx.WeatherMessage_METAR, 1), PRIORITY_CAROUSEL)
+	idle := make(chan bool)
+	go func() {
+		q.waitIdle()
+		idle <- true
+	}()
+	q.pop()
+	select {
+	case <-idle:
+		t.Fatalf("idle while the last packet is still being sent")
+	case <-time.After(20 * time.Millisecond):
+	}
+	go q.pop() // Back for more, the last one is done.
+	select {
+	case <-idle:
+	case <-time.After(time.Second):
+		t.Fatalf("never idle")
+	}
+}
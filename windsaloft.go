@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	WINDS_ALOFT_URL = "https://aviationweather.gov/api/data/windtemp?region=all&level=low&fcst=06"
+
+	WINDS_LIGHT_VARIABLE = 990 // Direction value used for "light and variable" (9900).
+	WINDS_NO_TEMP        = 127 // Encoded temperature when the level has none (3000 ft).
+)
+
+// windsAloftLevel is one forecast group of an FB bulletin.
+type windsAloftLevel struct {
+	Altitude  int // Feet MSL.
+	Direction int // Degrees true, WINDS_LIGHT_VARIABLE for light and variable.
+	Speed     int // Knots.
+	Temp      int // Degrees C.
+	HasTemp   bool
+}
+
+// windsAloftStation is the forecast for one FB station.
+type windsAloftStation struct {
+	Ident     string // Three letter FB ident, e.g. "DEN".
+	BasedOn   time.Time
+	ValidTime time.Time
+	UseFrom   time.Time
+	UseTo     time.Time
+	Levels    []windsAloftLevel
+}
+
+// encodeWindsAloft packs levels four bytes each: altitude (thousands of feet), direction (tens of degrees,
+// 99 = light and variable), speed (knots) and temperature (signed, WINDS_NO_TEMP = none).
+func encodeWindsAloft(levels []windsAloftLevel) []byte {
+	buf := make([]byte, 0, len(levels)*4)
+	for _, l := range levels {
+		temp := int8(WINDS_NO_TEMP)
+		if l.HasTemp {
+			temp = int8(l.Temp)
+		}
+		speed := l.Speed
+		if speed > 255 {
+			speed = 255
+		}
+		buf = append(buf, byte(l.Altitude/1000), byte(l.Direction/10), byte(speed), byte(temp))
+	}
+	return buf
+}
+
+func decodeWindsAloft(data []byte) ([]windsAloftLevel, error) {
+	if len(data)%4 != 0 {
+		return nil, errors.New("decodeWindsAloft(): bad length.")
+	}
+	levels := make([]windsAloftLevel, 0, len(data)/4)
+	for i := 0; i < len(data); i += 4 {
+		l := windsAloftLevel{
+			Altitude:  int(data[i]) * 1000,
+			Direction: int(data[i+1]) * 10,
+			Speed:     int(data[i+2]),
+			Temp:      int(int8(data[i+3])),
+		}
+		l.HasTemp = l.Temp != WINDS_NO_TEMP
+		if !l.HasTemp {
+			l.Temp = 0
+		}
+		levels = append(levels, l)
+	}
+	return levels, nil
+}
+
+// formatFBGroup formats a level the way it appears in an FB bulletin: "2315", "2420+08", "731960".
+func formatFBGroup(l windsAloftLevel) string {
+	var wind string
+	switch {
+	case l.Direction == WINDS_LIGHT_VARIABLE || l.Speed < 5:
+		wind = "9900"
+	case l.Speed >= 100:
+		wind = fmt.Sprintf("%02d%02d", (l.Direction+5)/10%36+50, l.Speed-100)
+	default:
+		dir := (l.Direction + 5) / 10 % 36
+		if dir == 0 {
+			dir = 36
+		}
+		wind = fmt.Sprintf("%02d%02d", dir, l.Speed)
+	}
+	if !l.HasTemp {
+		return wind
+	}
+	if l.Altitude > 24000 {
+		// Temperatures above 24000 ft are always negative and the sign is dropped.
+		return fmt.Sprintf("%s%02d", wind, -l.Temp)
+	}
+	if l.Temp < 0 {
+		return fmt.Sprintf("%s-%02d", wind, -l.Temp)
+	}
+	return fmt.Sprintf("%s+%02d", wind, l.Temp)
+}
+
+// parseFBGroup is the inverse of formatFBGroup.
+func parseFBGroup(altitude int, group string) (windsAloftLevel, error) {
+	l := windsAloftLevel{Altitude: altitude}
+	if len(group) < 4 {
+		return l, errors.New("parseFBGroup(): short group " + group)
+	}
+	dir, err := strconv.Atoi(group[0:2])
+	if err != nil {
+		return l, err
+	}
+	speed, err := strconv.Atoi(group[2:4])
+	if err != nil {
+		return l, err
+	}
+	switch {
+	case dir == 99:
+		l.Direction = WINDS_LIGHT_VARIABLE
+	case dir > 36:
+		l.Direction = (dir - 50) * 10
+		l.Speed = speed + 100
+	default:
+		l.Direction = dir * 10
+		l.Speed = speed
+	}
+	if len(group) > 4 {
+		temp, err := strconv.Atoi(group[4:])
+		if err != nil {
+			return l, err
+		}
+		if len(group) == 6 && temp > 0 {
+			temp = -temp // Implied negative above 24000 ft.
+		}
+		l.Temp = temp
+		l.HasTemp = true
+	}
+	return l, nil
+}
+
+// parseDDHHMM interprets a "ddhhmm" time as the occurrence closest to now.
+func parseDDHHMM(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	if len(s) != 6 {
+		return time.Time{}, errors.New("parseDDHHMM(): bad time " + s)
+	}
+	day, err1 := strconv.Atoi(s[0:2])
+	hour, err2 := strconv.Atoi(s[2:4])
+	min, err3 := strconv.Atoi(s[4:6])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return time.Time{}, errors.New("parseDDHHMM(): bad time " + s)
+	}
+	// Bulletins can straddle a month boundary. Try this month and its neighbours that have the day.
+	now = now.UTC()
+	var best time.Time
+	for _, m := range []int{-1, 0, 1} {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, m, 0)
+		t := time.Date(month.Year(), month.Month(), day, hour, min, 0, 0, time.UTC)
+		if t.Month() != month.Month() {
+			continue
+		}
+		if best.IsZero() || math.Abs(float64(t.Sub(now))) < math.Abs(float64(best.Sub(now))) {
+			best = t
+		}
+	}
+	if best.IsZero() {
+		return time.Time{}, errors.New("parseDDHHMM(): bad time " + s)
+	}
+	return best, nil
+}
+
+// parseFBBulletin reads an FB winds and temperatures aloft bulletin. Groups are located by the column
+// their altitude heading ends in, because levels with no forecast are left blank.
+func parseFBBulletin(r io.Reader, now time.Time) ([]windsAloftStation, error) {
+	var stations []windsAloftStation
+	var basedOn, validTime, useFrom, useTo time.Time
+	var altitudes []int
+	var ends []int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case len(fields) >= 4 && fields[0] == "DATA" && fields[1] == "BASED":
+			basedOn, _ = parseDDHHMM(fields[3], now)
+		case fields[0] == "VALID" && len(fields) >= 5:
+			validTime, _ = parseDDHHMM(fields[1], now)
+			// "FOR USE 1400-2100Z."
+			use := strings.Split(strings.TrimRight(fields[4], "Z."), "-")
+			if len(use) == 2 {
+				useFrom, _ = parseDDHHMM(validTime.Format("02")+use[0], validTime)
+				useTo, _ = parseDDHHMM(validTime.Format("02")+use[1], validTime)
+				if useFrom.After(validTime) {
+					useFrom = useFrom.AddDate(0, 0, -1)
+				}
+				if useTo.Before(validTime) {
+					useTo = useTo.AddDate(0, 0, 1)
+				}
+			}
+		case fields[0] == "FT":
+			altitudes = altitudes[:0]
+			ends = ends[:0]
+			col := 0
+			for _, f := range fields[1:] {
+				alt, err := strconv.Atoi(f)
+				if err != nil {
+					return nil, errors.New("parseFBBulletin(): bad altitude heading " + f)
+				}
+				col = strings.Index(line[col:], f) + col + len(f)
+				altitudes = append(altitudes, alt)
+				ends = append(ends, col)
+			}
+		case len(altitudes) > 0 && len(fields[0]) == 3 && len(fields) > 1:
+			s := windsAloftStation{Ident: fields[0], BasedOn: basedOn, ValidTime: validTime, UseFrom: useFrom, UseTo: useTo}
+			for i, alt := range altitudes {
+				width := 7
+				if alt <= 3000 {
+					width = 4
+				} else if alt > 24000 {
+					width = 6
+				}
+				end := ends[i]
+				if end > len(line) {
+					end = len(line)
+				}
+				start := ends[i] - width
+				if start < 3 || start >= end {
+					continue
+				}
+				group := strings.TrimSpace(line[start:end])
+				if len(group) == 0 {
+					continue
+				}
+				l, err := parseFBGroup(alt, group)
+				if err != nil {
+					continue
+				}
+				s.Levels = append(s.Levels, l)
+			}
+			stations = append(stations, s)
+		}
+	}
+	return stations, scanner.Err()
+}
+
+// formatFBText renders a station forecast as UAT text, e.g.
+// "WINDS DEN 191800Z FT 3000 6000 9000 ...\n              2315 2420+08 ...".
+func formatFBText(ident string, validTime time.Time, levels []windsAloftLevel) string {
+	header := fmt.Sprintf("WINDS %s %s FT", ident, validTime.UTC().Format("021504Z"))
+	groups := strings.Repeat(" ", len(header))
+	for _, l := range levels {
+		alt := strconv.Itoa(l.Altitude)
+		group := formatFBGroup(l)
+		width := len(alt)
+		if len(group) > width {
+			width = len(group)
+		}
+		header += fmt.Sprintf(" %*s", width, alt)
+		groups += fmt.Sprintf(" %*s", width, group)
+	}
+	return header + "\n" + groups
+}
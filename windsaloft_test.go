@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// An FD1US1 bulletin as issued. High stations leave the levels below ground blank, ATL has light and
+// variable wind at 3000, ABI 119 knots at 39000.
+const testFBBulletin = `000
+FBUS31 KWNO 191359
+FD1US1
+DATA BASED ON 191200Z
+VALID 191800Z   FOR USE 1400-2100Z. TEMPS NEG ABV 24000
+
+FT  3000    6000    9000   12000   18000   24000  30000  34000  39000
+ABI 1607 1908+15 2113+10 2313+05 2423-09 2538-20 255435 256145 731960
+ATL 9900 3406+12 3511+07 3412+02 3118-13 2835-25 274540 275149 274959
+BOI      3113+03 3011-02 2916-06 2732-19 2648-31 266046 265756 275564
+DEN              2719-03 2726-08 2736-19 2751-31 276946 277256 276865
+ALS                      2630-06 2645-17 2762-29 268145 268155 268865
+`
+
+func TestParseFBBulletin(t *testing.T) {
+	now := time.Date(2019, 10, 19, 14, 5, 0, 0, time.UTC)
+	stations, err := parseFBBulletin(strings.NewReader(testFBBulletin), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stations) != 5 {
+		t.Fatalf("%d stations", len(stations))
+	}
+	s := stations[0]
+	if !s.BasedOn.Equal(time.Date(2019, 10, 19, 12, 0, 0, 0, time.UTC)) || !s.ValidTime.Equal(time.Date(2019, 10, 19, 18, 0, 0, 0, time.UTC)) ||
+		!s.UseFrom.Equal(time.Date(2019, 10, 19, 14, 0, 0, 0, time.UTC)) || !s.UseTo.Equal(time.Date(2019, 10, 19, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("times: based on %s, valid %s, use %s-%s", s.BasedOn, s.ValidTime, s.UseFrom, s.UseTo)
+	}
+	tests := []struct {
+		ident string
+		first windsAloftLevel // Lowest level with a forecast.
+		last  windsAloftLevel
+		count int
+	}{
+		{"ABI", windsAloftLevel{3000, 160, 7, 0, false}, windsAloftLevel{39000, 230, 119, -60, true}, 9},
+		{"ATL", windsAloftLevel{3000, WINDS_LIGHT_VARIABLE, 0, 0, false}, windsAloftLevel{39000, 270, 49, -59, true}, 9},
+		{"BOI", windsAloftLevel{6000, 310, 13, 3, true}, windsAloftLevel{39000, 270, 55, -64, true}, 8},
+		{"DEN", windsAloftLevel{9000, 270, 19, -3, true}, windsAloftLevel{39000, 270, 68, -65, true}, 7},
+		{"ALS", windsAloftLevel{12000, 260, 30, -6, true}, windsAloftLevel{39000, 260, 88, -65, true}, 6},
+	}
+	for i, tt := range tests {
+		s := stations[i]
+		if s.Ident != tt.ident || len(s.Levels) != tt.count {
+			t.Errorf("station %d: %s with %d levels, want %s with %d", i, s.Ident, len(s.Levels), tt.ident, tt.count)
+			continue
+		}
+		if s.Levels[0] != tt.first || s.Levels[len(s.Levels)-1] != tt.last {
+			t.Errorf("%s: levels %+v ... %+v, want %+v ... %+v", s.Ident, s.Levels[0], s.Levels[len(s.Levels)-1], tt.first, tt.last)
+		}
+	}
+}
+
+func TestFBGroupRoundTrip(t *testing.T) {
+	tests := []struct {
+		altitude int
+		group    string
+	}{
+		{3000, "1607"},
+		{3000, "9900"},
+		{6000, "1908+15"},
+		{12000, "2916-06"},
+		{24000, "2538-20"},
+		{30000, "255435"},
+		{39000, "731960"},
+		{39000, "990060"},
+	}
+	for _, tt := range tests {
+		l, err := parseFBGroup(tt.altitude, tt.group)
+		if err != nil {
+			t.Errorf("parseFBGroup(%d, %s): %s", tt.altitude, tt.group, err.Error())
+			continue
+		}
+		if got := formatFBGroup(l); got != tt.group {
+			t.Errorf("%s parsed to %+v, formats as %s", tt.group, l, got)
+		}
+	}
+}
+
+func TestWindsAloftCodec(t *testing.T) {
+	stations, err := parseFBBulletin(strings.NewReader(testFBBulletin), time.Date(2019, 10, 19, 14, 5, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range stations {
+		levels, err := decodeWindsAloft(encodeWindsAloft(s.Levels))
+		if err != nil {
+			t.Fatalf("%s: %s", s.Ident, err.Error())
+		}
+		if len(levels) != len(s.Levels) {
+			t.Fatalf("%s: %d levels decoded, %d sent", s.Ident, len(levels), len(s.Levels))
+		}
+		for i := range levels {
+			if levels[i] != s.Levels[i] {
+				t.Errorf("%s: level %d decoded as %+v, sent %+v", s.Ident, i, levels[i], s.Levels[i])
+			}
+		}
+	}
+	if _, err := decodeWindsAloft([]byte{3, 16, 7}); err == nil {
+		t.Errorf("short data decoded")
+	}
+}
+
+func TestParseDDHHMM(t *testing.T) {
+	tests := []struct {
+		s    string
+		now  time.Time
+		want time.Time
+	}{
+		{"191200Z", time.Date(2019, 10, 19, 14, 0, 0, 0, time.UTC), time.Date(2019, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{"311800Z", time.Date(2019, 11, 1, 2, 0, 0, 0, time.UTC), time.Date(2019, 10, 31, 18, 0, 0, 0, time.UTC)},
+		{"010600Z", time.Date(2019, 10, 31, 22, 0, 0, 0, time.UTC), time.Date(2019, 11, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseDDHHMM(tt.s, tt.now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDDHHMM(%s, %s) = %s, %v, want %s", tt.s, tt.now, got, err, tt.want)
+		}
+	}
+}